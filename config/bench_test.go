@@ -0,0 +1,98 @@
+package config
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+const benchSingleCloud = `
+clouds:
+  prod:
+    region_name: RegionOne
+    interface: public
+    identity_api_version: 3
+    auth:
+      auth_url: https://keystone.example.com:5000/v3
+      username: admin
+      password: s3cret
+      project_name: admin
+      user_domain_name: Default
+      project_domain_name: Default
+`
+
+// benchClouds returns a document defining n clouds like the one in
+// benchSingleCloud.
+func benchClouds(n int) string {
+	var b strings.Builder
+	b.WriteString("clouds:\n")
+	entry := strings.SplitN(benchSingleCloud, "\n", 4)[3]
+	for i := 0; i < n; i++ {
+		b.WriteString("  cloud" + strconv.Itoa(i) + ":\n")
+		b.WriteString(entry)
+	}
+	return b.String()
+}
+
+func TestSinglePassDecodeMatchesGeneric(t *testing.T) {
+	// WithEnvExpansion takes the generic path, which must give the same
+	// result for content with nothing to expand.
+	for _, doc := range []string{benchSingleCloud, benchClouds(3), benchSingleCloud + "default_cloud: prod\nother: [1, {a: b}]\n"} {
+		fast, err := FromBytes([]byte(doc))
+		if err != nil {
+			t.Fatal(err)
+		}
+		generic, err := FromBytes([]byte(doc), WithEnvExpansion())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := fast.AllIncludingDisabled(), generic.AllIncludingDisabled(); !reflect.DeepEqual(got, want) {
+			t.Errorf("single-pass decode = %+v, generic decode = %+v", got, want)
+		}
+		fd, _ := fast.DefaultName()
+		gd, _ := generic.DefaultName()
+		if fd != gd {
+			t.Errorf("single-pass default = %q, generic default = %q", fd, gd)
+		}
+	}
+}
+
+func TestSinglePassDecodeAllocatesLess(t *testing.T) {
+	b := []byte(benchSingleCloud)
+	fast := testing.AllocsPerRun(50, func() { FromBytes(b) })
+	generic := testing.AllocsPerRun(50, func() { FromBytes(b, WithEnvExpansion()) })
+	if fast >= generic {
+		t.Errorf("single-pass decode made %v allocations, generic decode %v; want fewer", fast, generic)
+	}
+}
+
+func BenchmarkFromBytesSingleCloud(b *testing.B) {
+	doc := []byte(benchSingleCloud)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := FromBytes(doc); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkFromBytesSingleCloudGeneric(b *testing.B) {
+	doc := []byte(benchSingleCloud)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := FromBytes(doc, WithEnvExpansion()); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkFromBytesManyClouds(b *testing.B) {
+	doc := []byte(benchClouds(100))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := FromBytes(doc); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
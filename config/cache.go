@@ -0,0 +1,99 @@
+package config
+
+import (
+	"github.com/gophercloud/gophercloud"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// cacheExpiryMargin is how long before its token expires a cached client is
+// considered stale and replaced.
+const cacheExpiryMargin = 5 * time.Minute
+
+// clientCache holds authenticated ProviderClients, and the domain IDs learned
+// from their tokens, keyed by cloud name.
+type clientCache struct {
+	mu      sync.Mutex
+	clients map[string]cachedClient
+	domains map[string]cachedDomains
+}
+
+// newClientCache returns an empty clientCache.
+func newClientCache() *clientCache {
+	return &clientCache{
+		clients: map[string]cachedClient{},
+		domains: map[string]cachedDomains{},
+	}
+}
+
+// cachedClient is a ProviderClient along with what it was built from, so it
+// can be discarded if the cloud’s config or the call’s options that affect
+// authentication differ.
+type cachedClient struct {
+	client  *gophercloud.ProviderClient
+	cloud   cloud
+	opts    clientOptions
+	expires time.Time
+}
+
+// get returns the cached client for a cloud if it was built from the same
+// config and options and its token is not near expiry.
+func (cc *clientCache) get(name string, v cloud, co *clientOptions) *gophercloud.ProviderClient {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	e, ok := cc.clients[name]
+	if !ok || e.opts != co.authKey() || !reflect.DeepEqual(e.cloud, v) {
+		return nil
+	}
+	if time.Now().Add(cacheExpiryMargin).After(e.expires) {
+		delete(cc.clients, name)
+		return nil
+	}
+	return e.client
+}
+
+// put caches a client for a cloud. Clients whose token expiry is unknown are
+// not cached.
+func (cc *clientCache) put(name string, v cloud, co *clientOptions, p *gophercloud.ProviderClient) {
+	expires, err := tokenExpiry(p)
+	if err != nil {
+		return
+	}
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	cc.clients[name] = cachedClient{client: p, cloud: v, opts: co.authKey(), expires: expires}
+}
+
+// InvalidateClient satisfies the Config interface.
+func (c *configImpl) InvalidateClient(name string) {
+	if c.cache == nil {
+		return
+	}
+	c.cache.mu.Lock()
+	defer c.cache.mu.Unlock()
+	delete(c.cache.clients, name)
+	delete(c.cache.domains, name)
+}
+
+// CachedTokenValid satisfies the Config interface.
+func (c *configImpl) CachedTokenValid(name string) (bool, time.Duration, error) {
+	v, err := c.cloud(name)
+	if err != nil {
+		return false, 0, err
+	}
+	if c.cache == nil {
+		return false, 0, nil
+	}
+	c.cache.mu.Lock()
+	defer c.cache.mu.Unlock()
+	e, ok := c.cache.clients[name]
+	if !ok || !reflect.DeepEqual(e.cloud, v) {
+		return false, 0, nil
+	}
+	remaining := time.Until(e.expires)
+	if remaining <= cacheExpiryMargin {
+		return false, remaining, nil
+	}
+	return true, remaining, nil
+}
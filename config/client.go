@@ -0,0 +1,325 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"github.com/gophercloud/gophercloud"
+	"github.com/gophercloud/gophercloud/openstack"
+	tokens2 "github.com/gophercloud/gophercloud/openstack/identity/v2/tokens"
+	tokens3 "github.com/gophercloud/gophercloud/openstack/identity/v3/tokens"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ClientOption adjusts how Authenticate, ServiceClient, and related helpers
+// build a client for a single call, without changing the stored config.
+type ClientOption func(*clientOptions)
+
+// clientOptions holds the settings applied by ClientOptions.
+type clientOptions struct {
+	insecure bool
+	endpoint gophercloud.EndpointOpts
+}
+
+// newClientOptions returns the default settings with opts applied in order.
+func newClientOptions(opts []ClientOption) *clientOptions {
+	co := &clientOptions{}
+	for _, opt := range opts {
+		opt(co)
+	}
+	return co
+}
+
+// WithInsecureSkipVerify disables TLS certificate verification for this call
+// only, regardless of the cloud’s verify setting.
+//
+// This exposes the connection, including the credentials sent to
+// authenticate, to interception by anyone able to impersonate the server. Use
+// it only for deliberate one-off access, such as to a staging endpoint with a
+// self-signed certificate, and never by default.
+func WithInsecureSkipVerify() ClientOption {
+	return func(co *clientOptions) {
+		co.insecure = true
+	}
+}
+
+// WithEndpointOpts overrides how ServiceClient selects the service endpoint
+// from the catalog, for this call only: each non-empty field of eo, such as
+// Region or Availability, takes precedence over the cloud’s region_name and
+// interface. The stored config is unchanged, so later calls without the
+// option use the cloud’s own settings again.
+func WithEndpointOpts(eo gophercloud.EndpointOpts) ClientOption {
+	return func(co *clientOptions) {
+		co.endpoint = eo
+	}
+}
+
+// authKey returns the subset of the options that affect authentication, for
+// deciding whether a cached client can be reused.
+func (co clientOptions) authKey() clientOptions {
+	co.endpoint = gophercloud.EndpointOpts{}
+	return co
+}
+
+// Authenticate satisfies the Config interface.
+func (c *configImpl) Authenticate(name string, opts ...ClientOption) (*gophercloud.ProviderClient, error) {
+	v, err := c.cloud(name)
+	if err != nil {
+		return nil, err
+	}
+	co := newClientOptions(opts)
+	if c.cache != nil {
+		if p := c.cache.get(name, v, co); p != nil {
+			return p, nil
+		}
+	}
+	av, err := c.withPrompted(name, v)
+	if err != nil {
+		return nil, err
+	}
+	if c.cache != nil {
+		av.auth = c.cache.withDomainIDs(name, av)
+	}
+	p, err := authenticate(context.Background(), name, av, co)
+	if err != nil {
+		return nil, err
+	}
+	if c.cache != nil {
+		c.cache.put(name, v, co, p)
+		c.cache.putDomains(name, v, p)
+	}
+	return p, nil
+}
+
+// authenticate returns a new ProviderClient authenticated against a cloud.
+// Requests made while authenticating are bound to ctx; the returned client is
+// not, so a cached client outlives the context used to create it.
+func authenticate(ctx context.Context, name string, v cloud, co *clientOptions) (*gophercloud.ProviderClient, error) {
+	if v.effectiveAuthType() == tokenEndpointAuthType {
+		return tokenEndpointClient(name, v, co)
+	}
+	p, err := openstack.NewClient(v.auth.IdentityEndpoint)
+	if err != nil {
+		s := "config: cannot authenticate to cloud `" + name + "`: " + err.Error()
+		return nil, errors.New(s)
+	}
+	if err := setTransport(p, v, co); err != nil {
+		return nil, err
+	}
+	p.Context = ctx
+	defer func() { p.Context = nil }()
+	if err := openstack.Authenticate(p, cloneAuthOptions(v.auth)); err != nil {
+		s := "config: cannot authenticate to cloud `" + name + "`: " + err.Error()
+		return nil, errors.New(s)
+	}
+	return p, nil
+}
+
+// setTransport makes a ProviderClient connect with a cloud’s TLS settings and
+// send its headers, if it has any.
+func setTransport(p *gophercloud.ProviderClient, v cloud, co *clientOptions) error {
+	tc, err := v.tls.tlsConfig(co.insecure)
+	if err != nil {
+		return err
+	}
+	if tc != nil {
+		t := http.DefaultTransport.(*http.Transport).Clone()
+		t.TLSClientConfig = tc
+		p.HTTPClient.Transport = t
+	}
+	if len(v.headers) > 0 {
+		p.HTTPClient.Transport = &headerTransport{base: p.HTTPClient.Transport, headers: v.headers}
+	}
+	return nil
+}
+
+// ClientV satisfies the Config interface.
+func (c *configImpl) ClientV(name string, opts ...ClientOption) (*gophercloud.ProviderClient, string, error) {
+	v, err := c.cloud(name)
+	if err != nil {
+		return nil, "", err
+	}
+	switch iv := v.identityVersion(); iv {
+	case "", "2", "3":
+	default:
+		s := "config: cloud `" + name + "` has unsupported identity API version `" + iv + "`"
+		return nil, "", errors.New(s)
+	}
+	p, err := c.Authenticate(name, opts...)
+	if err != nil {
+		return nil, "", err
+	}
+	switch p.GetAuthResult().(type) {
+	case tokens2.CreateResult:
+		return p, "2", nil
+	case tokens3.CreateResult, tokens3.GetResult:
+		return p, "3", nil
+	}
+	return nil, "", errors.New("config: cannot tell which identity API version cloud `" + name + "` used")
+}
+
+// ServiceClient satisfies the Config interface.
+func (c *configImpl) ServiceClient(name, service string, opts ...ClientOption) (*gophercloud.ServiceClient, error) {
+	v, err := c.cloud(name)
+	if err != nil {
+		return nil, err
+	}
+	if service == "block-storage" {
+		service = "volume"
+	}
+	newClient, microversion, err := serviceClientFunc(service, v.apiVersions[service])
+	if err != nil {
+		return nil, err
+	}
+	override := newClientOptions(opts).endpoint
+	eo := overrideEndpointOpts(gophercloud.EndpointOpts{Region: v.region}, override)
+	avs, err := v.availabilities(eo.Region)
+	if err != nil {
+		return nil, errors.New("config: cloud `" + name + "`: " + err.Error())
+	}
+	if override.Availability != "" {
+		avs = []gophercloud.Availability{override.Availability}
+	}
+	p, err := c.Authenticate(name, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if v.effectiveAuthType() == tokenEndpointAuthType {
+		return tokenEndpointServiceClient(p, v, service, microversion), nil
+	}
+	var sc *gophercloud.ServiceClient
+	for _, a := range avs {
+		eo.Availability = a
+		if sc, err = newClient(p, eo); err == nil {
+			break
+		}
+	}
+	if err != nil {
+		s := "config: cannot create " + service + " client for cloud `" + name + "`: " + err.Error()
+		return nil, errors.New(s)
+	}
+	sc.Microversion = microversion
+	return sc, nil
+}
+
+// newServiceClientFunc is the signature of gophercloud’s service client
+// constructors, such as openstack.NewComputeV2.
+type newServiceClientFunc func(*gophercloud.ProviderClient, gophercloud.EndpointOpts) (*gophercloud.ServiceClient, error)
+
+// serviceClientFunc returns the constructor for a service at an API version,
+// along with the microversion to pin, if any.
+func serviceClientFunc(service, version string) (newServiceClientFunc, string, error) {
+	major, microversion := version, ""
+	if i := strings.Index(version, "."); i >= 0 {
+		major = version[:i]
+		microversion = version
+	}
+	var f newServiceClientFunc
+	switch service {
+	case "compute":
+		if major == "" || major == "2" {
+			f = openstack.NewComputeV2
+		}
+	case "identity":
+		microversion = ""
+		switch major {
+		case "2":
+			f = openstack.NewIdentityV2
+		case "", "3":
+			f = openstack.NewIdentityV3
+		}
+	case "image":
+		microversion = ""
+		if major == "" || major == "2" {
+			f = openstack.NewImageServiceV2
+		}
+	case "network":
+		microversion = ""
+		if major == "" || major == "2" {
+			f = openstack.NewNetworkV2
+		}
+	case "volume":
+		switch major {
+		case "1":
+			f = openstack.NewBlockStorageV1
+		case "2":
+			f = openstack.NewBlockStorageV2
+		case "", "3":
+			f = openstack.NewBlockStorageV3
+		}
+	default:
+		return nil, "", errors.New("config: unsupported service `" + service + "`")
+	}
+	if f == nil {
+		s := "config: unsupported " + service + " API version `" + version + "`"
+		return nil, "", errors.New(s)
+	}
+	return f, microversion, nil
+}
+
+// overrideEndpointOpts returns eo with each non-empty field of override
+// replacing its own.
+func overrideEndpointOpts(eo, override gophercloud.EndpointOpts) gophercloud.EndpointOpts {
+	if override.Type != "" {
+		eo.Type = override.Type
+	}
+	if override.Name != "" {
+		eo.Name = override.Name
+	}
+	if override.Region != "" {
+		eo.Region = override.Region
+	}
+	if override.Availability != "" {
+		eo.Availability = override.Availability
+	}
+	return eo
+}
+
+// availability converts an interface name from clouds.yaml, such as internal
+// or its older form internalURL, to a gophercloud.Availability. An empty name
+// leaves the choice to gophercloud, which uses public.
+func availability(iface string) (gophercloud.Availability, error) {
+	switch strings.TrimSuffix(iface, "URL") {
+	case "":
+		return "", nil
+	case "public":
+		return gophercloud.AvailabilityPublic, nil
+	case "internal":
+		return gophercloud.AvailabilityInternal, nil
+	case "admin":
+		return gophercloud.AvailabilityAdmin, nil
+	}
+	return "", errors.New("unsupported interface `" + iface + "`")
+}
+
+// TokenInfo satisfies the Config interface.
+func (c *configImpl) TokenInfo(name string, opts ...ClientOption) (time.Time, error) {
+	p, err := c.Authenticate(name, opts...)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return tokenExpiry(p)
+}
+
+// tokenExpiry returns the expiration time of the token held by an
+// authenticated ProviderClient.
+func tokenExpiry(p *gophercloud.ProviderClient) (time.Time, error) {
+	switch r := p.GetAuthResult().(type) {
+	case interface {
+		ExtractToken() (*tokens3.Token, error)
+	}:
+		t, err := r.ExtractToken()
+		if err != nil {
+			return time.Time{}, errors.New("config: " + err.Error())
+		}
+		return t.ExpiresAt, nil
+	case tokens2.CreateResult:
+		t, err := r.ExtractToken()
+		if err != nil {
+			return time.Time{}, errors.New("config: " + err.Error())
+		}
+		return t.ExpiresAt, nil
+	}
+	return time.Time{}, errors.New("config: token expiry is not available")
+}
@@ -0,0 +1,142 @@
+package config
+
+import (
+	"encoding/json"
+	"github.com/gophercloud/gophercloud"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// fakeKeystone starts an identity v3 service that issues a token for any
+// request, with a catalog listing public and internal compute and volume
+// endpoints in RegionOne and RegionTwo, at paths beginning with the region and
+// interface. It returns the service’s auth URL.
+func fakeKeystone(t *testing.T) string {
+	t.Helper()
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/v3/auth/tokens" {
+			http.NotFound(w, r)
+			return
+		}
+		endpoint := func(typ, path string) map[string]interface{} {
+			var eps []map[string]interface{}
+			for _, region := range []string{"RegionOne", "RegionTwo"} {
+				for _, iface := range []string{"public", "internal"} {
+					eps = append(eps, map[string]interface{}{
+						"interface": iface,
+						"region":    region,
+						"region_id": region,
+						"url":       srv.URL + "/" + region + "/" + iface + path,
+					})
+				}
+			}
+			return map[string]interface{}{"type": typ, "endpoints": eps}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-Subject-Token", "tok")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"token": map[string]interface{}{
+				"expires_at": "2999-01-01T00:00:00.000000Z",
+				"catalog": []map[string]interface{}{
+					endpoint("compute", "/compute/v2.1/"),
+					endpoint("volumev3", "/volume/v3/"),
+				},
+			},
+		})
+	}))
+	t.Cleanup(srv.Close)
+	return srv.URL + "/v3"
+}
+
+func TestServiceClientMicroversion(t *testing.T) {
+	authURL := fakeKeystone(t)
+	conf, err := FromBytes([]byte(`
+clouds:
+  pinned:
+    region_name: RegionOne
+    compute_api_version: "2.79"
+    volume_api_version: "3.50"
+    auth: {auth_url: ` + authURL + `, username: u, password: p, user_domain_name: Default}
+  major:
+    region_name: RegionOne
+    compute_api_version: "2"
+    auth: {auth_url: ` + authURL + `, username: u, password: p, user_domain_name: Default}
+  unset:
+    region_name: RegionOne
+    auth: {auth_url: ` + authURL + `, username: u, password: p, user_domain_name: Default}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		cloud, service, want string
+	}{
+		{"pinned", "compute", "2.79"},
+		{"pinned", "volume", "3.50"},
+		{"pinned", "block-storage", "3.50"},
+		{"major", "compute", ""},
+		{"unset", "compute", ""},
+		{"unset", "volume", ""},
+	} {
+		sc, err := conf.ServiceClient(tt.cloud, tt.service)
+		if err != nil {
+			t.Errorf("ServiceClient(%s, %s): %v", tt.cloud, tt.service, err)
+			continue
+		}
+		if sc.Microversion != tt.want {
+			t.Errorf("ServiceClient(%s, %s).Microversion = %q, want %q", tt.cloud, tt.service, sc.Microversion, tt.want)
+		}
+	}
+}
+
+func TestServiceClientUnsupportedVersion(t *testing.T) {
+	conf, err := FromBytes([]byte(`
+clouds:
+  a:
+    compute_api_version: "3.1"
+    auth: {auth_url: http://127.0.0.1:1/v3, username: u, password: p}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = conf.ServiceClient("a", "compute")
+	if want := "config: unsupported compute API version `3.1`"; err == nil || err.Error() != want {
+		t.Errorf("ServiceClient error = %v, want %s", err, want)
+	}
+}
+
+func TestServiceClientEndpointOverride(t *testing.T) {
+	authURL := fakeKeystone(t)
+	conf, err := FromBytes([]byte(`
+clouds:
+  a:
+    region_name: RegionOne
+    interface: public
+    auth: {auth_url: ` + authURL + `, username: u, password: p, user_domain_name: Default}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	base := strings.TrimSuffix(authURL, "/v3")
+	for _, tt := range []struct {
+		opts []ClientOption
+		want string
+	}{
+		{nil, "/RegionOne/public/compute/v2.1/"},
+		{[]ClientOption{WithEndpointOpts(gophercloud.EndpointOpts{Availability: gophercloud.AvailabilityInternal})}, "/RegionOne/internal/compute/v2.1/"},
+		{[]ClientOption{WithEndpointOpts(gophercloud.EndpointOpts{Region: "RegionTwo"})}, "/RegionTwo/public/compute/v2.1/"},
+		{nil, "/RegionOne/public/compute/v2.1/"},
+	} {
+		sc, err := conf.ServiceClient("a", "compute", tt.opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := base + tt.want; sc.Endpoint != want {
+			t.Errorf("ServiceClient endpoint = %s, want %s", sc.Endpoint, want)
+		}
+	}
+}
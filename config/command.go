@@ -0,0 +1,50 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// defaultCommandTimeout is how long a password_command may run by default.
+const defaultCommandTimeout = 5 * time.Second
+
+// commandWaitDelay is how long runPasswordCommand waits, once the shell has
+// exited or been killed, for processes it left behind to close its output.
+const commandWaitDelay = 100 * time.Millisecond
+
+// runPasswordCommand runs a password_command through the shell and returns
+// its standard output without the trailing newline. If the command runs
+// longer than timeout, it is killed along with any processes it started.
+// Processes that escape it, such as by starting a session of their own, are
+// not waited for beyond a short delay, so the timeout bounds the call. Errors
+// never include the command’s output, since it may hold the secret.
+func runPasswordCommand(command string, timeout time.Duration) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	cmd := shellCommand(command)
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	cmd.WaitDelay = commandWaitDelay
+	if err := cmd.Start(); err != nil {
+		return "", errors.New("cannot run password_command: " + err.Error())
+	}
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+	select {
+	case err := <-done:
+		// A process left running in the background may hold the output
+		// open after the shell exits; what the shell wrote still counts.
+		if err != nil && !errors.Is(err, exec.ErrWaitDelay) {
+			return "", errors.New("password_command failed: " + err.Error())
+		}
+	case <-ctx.Done():
+		killCommand(cmd)
+		<-done
+		return "", errors.New("password_command timed out after " + timeout.String())
+	}
+	return strings.TrimRight(out.String(), "\r\n"), nil
+}
@@ -0,0 +1,22 @@
+//go:build !windows
+// +build !windows
+
+package config
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// shellCommand returns a command that runs s through sh in a process group of
+// its own, so killCommand can stop any processes it starts as well.
+func shellCommand(s string) *exec.Cmd {
+	cmd := exec.Command("sh", "-c", s)
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	return cmd
+}
+
+// killCommand kills a started command’s process group.
+func killCommand(cmd *exec.Cmd) {
+	_ = syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+}
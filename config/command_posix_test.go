@@ -0,0 +1,51 @@
+//go:build !windows
+// +build !windows
+
+package config
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRunPasswordCommand(t *testing.T) {
+	got, err := runPasswordCommand("printf 's3cret\\n'", time.Second)
+	if err != nil || got != "s3cret" {
+		t.Errorf("runPasswordCommand(printf) = %q, %v, want s3cret", got, err)
+	}
+	if _, err := runPasswordCommand("echo s3cret; exit 3", time.Second); err == nil {
+		t.Error("runPasswordCommand(exit 3) = nil error")
+	} else if strings.Contains(err.Error(), "s3cret") {
+		t.Errorf("error reveals the output: %v", err)
+	}
+}
+
+func TestRunPasswordCommandTimeoutIsBounded(t *testing.T) {
+	for _, command := range []string{
+		"sleep 3",
+		// A process in a session of its own escapes the process group kill
+		// but still holds the output open.
+		"setsid sleep 3 & sleep 3",
+	} {
+		start := time.Now()
+		_, err := runPasswordCommand(command, 200*time.Millisecond)
+		if err == nil || !strings.Contains(err.Error(), "timed out") {
+			t.Errorf("runPasswordCommand(%q) = %v, want a timeout", command, err)
+		}
+		if d := time.Since(start); d > 2*time.Second {
+			t.Errorf("runPasswordCommand(%q) took %v, want about the 200ms timeout", command, d)
+		}
+	}
+}
+
+func TestRunPasswordCommandBackgroundChild(t *testing.T) {
+	start := time.Now()
+	got, err := runPasswordCommand("echo s3cret; setsid sleep 3 &", time.Second)
+	if err != nil || got != "s3cret" {
+		t.Errorf("runPasswordCommand = %q, %v, want s3cret", got, err)
+	}
+	if d := time.Since(start); d > 2*time.Second {
+		t.Errorf("runPasswordCommand took %v waiting for a background child", d)
+	}
+}
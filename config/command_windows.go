@@ -0,0 +1,20 @@
+package config
+
+import (
+	"os/exec"
+	"strconv"
+)
+
+// shellCommand returns a command that runs s through cmd.
+func shellCommand(s string) *exec.Cmd {
+	return exec.Command("cmd", "/C", s)
+}
+
+// killCommand kills a started command along with the processes it started,
+// falling back to killing cmd alone if taskkill fails.
+func killCommand(cmd *exec.Cmd) {
+	pid := strconv.Itoa(cmd.Process.Pid)
+	if err := exec.Command("taskkill", "/T", "/F", "/PID", pid).Run(); err != nil {
+		_ = cmd.Process.Kill()
+	}
+}
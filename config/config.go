@@ -1,17 +1,56 @@
 // Package config loads OpenStack configuration from a clouds.yaml file.
+//
+// # Precedence
+//
+// A cloud’s settings may come from several sources. Where more than one sets
+// the same field, such as the password, the first of these wins:
+//
+//  1. OS_* environment variables, such as OS_PASSWORD, for the cloud
+//     selected by OS_CLOUD or DefaultName’s other rules (only with
+//     WithEnvOverlay)
+//  2. indirections in the cloud entry, such as password_env and
+//     password_command
+//  3. secret files (only with WithSecretDir)
+//  4. values in the cloud’s secure.yaml entry (only with FromReaders)
+//  5. values in the cloud entry itself
+//  6. values in the vendor profile the entry names with profile, from
+//     clouds-public.yaml (see WithPublicCloudsPath)
+//
+// A file:// URI given as a secret’s value is read in place of the value,
+// whichever of these set it, so it ranks with the layer that set it.
+// Transforms given by WithTransform run last, on the combined result.
 package config
 
 import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/tls"
 	"errors"
 	"github.com/gophercloud/gophercloud"
 	"gopkg.in/yaml.v2"
+	"io"
 	"io/ioutil"
+	"os"
 	"os/user"
 	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
 )
 
 // Config represents configuration data for all clouds defined in clouds.yaml.
 // Its methods are safe for concurrent use by multiple goroutines.
+//
+// A cloud entry with disabled: true is kept in the file but treated as if it
+// were not defined: it is omitted from Names and GetAll, and Get and other
+// per-cloud methods report it as not found. Only AllIncludingDisabled
+// includes it.
 type Config interface {
 	// TODO: Change Get to Cloud, and GetAll to AllClouds
 
@@ -19,129 +58,1382 @@ type Config interface {
 	// defined, this returns an error.
 	Get(name string) (gophercloud.AuthOptions, error)
 
+	// GetV3Scoped is like Get, but scopes the returned options to the
+	// given project using identity v3, leaving the stored config unchanged.
+	// If projectDomainName is empty, the project is found in the user’s
+	// domain. This returns an error if the cloud uses identity v2 or no
+	// domain is known for the project.
+	GetV3Scoped(name, projectName, projectDomainName string) (gophercloud.AuthOptions, error)
+
+	// GetUnscoped is like Get, but drops the project and any scope the
+	// cloud specifies, for authenticating unscoped to discover the user’s
+	// projects before re-scoping with GetV3Scoped. The user’s own domain
+	// is kept. This returns an error if the cloud is not defined or its
+	// auth type, such as v3applicationcredential, is always scoped.
+	GetUnscoped(name string) (gophercloud.AuthOptions, error)
+
+	// GetWithOverrides is like Get, but first sets the given auth fields,
+	// keyed by their names in clouds.yaml, such as project_id, as a CLI
+	// does for flags like --os-project-id. Aliases such as tenant_id set
+	// the field they stand for, and empty values are ignored. Setting one
+	// of the fields that name the same thing by ID or by name, such as
+	// project_id and project_name, clears the other, unless it is set
+	// too. Only the returned options change, not the stored config.
+	//
+	// region_name is accepted as well, and checked against the cloud’s
+	// region_name and regions list, if it sets them. As AuthOptions carry
+	// no region, pass it on to EndpointOpts or WithEndpointOpts to
+	// select endpoints there. This returns an error if the cloud is not
+	// defined, a key is not an auth field that holds a value or
+	// region_name, or the region is not one of the cloud’s; indirections
+	// such as password_env are not accepted.
+	GetWithOverrides(name string, overrides map[string]string) (gophercloud.AuthOptions, error)
+
 	// GetAll returns a map of all cloud configurations keyed by name. If
 	// no clouds are defined, this returns nil.
 	GetAll() map[string]gophercloud.AuthOptions
+
+	// DefaultName returns the name of the cloud to use when the caller
+	// does not name one. The first of these that applies wins:
+	//
+	//  1. $OS_CLOUD, if set
+	//  2. $OS_CLOUD_NAME, if set
+	//  3. the file’s top-level default_cloud field, if set
+	//  4. the only cloud, if exactly one is defined
+	//
+	// This returns an error if the chosen name is not a defined cloud, or
+	// if none of these applies.
+	DefaultName() (string, error)
+
+	// Default returns configuration for the cloud named by DefaultName.
+	Default() (gophercloud.AuthOptions, error)
+
+	// AllIncludingDisabled is like GetAll, but also includes clouds marked
+	// disabled, for tooling that needs to see every entry in the file.
+	AllIncludingDisabled() map[string]gophercloud.AuthOptions
+
+	// Names returns the sorted names of all clouds. If no clouds are
+	// defined, this returns nil.
+	Names() []string
+
+	// OrderedNames is like Names, but returns the clouds in the order the
+	// file defines them, for display and re-serialization that should
+	// follow the author’s layout. Clouds defined only in secure.yaml follow
+	// those of clouds.yaml, and clouds not defined by any file, such as
+	// those added by Merge, come last, sorted by name.
+	OrderedNames() []string
+
+	// NamesByAuthType is like Names, but returns only clouds whose
+	// auth_type is t. Entries that omit auth_type have the default type,
+	// password.
+	NamesByAuthType(t string) []string
+
+	// AllRedacted is like GetAll, but masks secrets such as passwords and
+	// tokens in the returned options, making them safe to display or log.
+	// Use GetAll for options to authenticate with.
+	AllRedacted() map[string]gophercloud.AuthOptions
+
+	// Authenticate returns a ProviderClient authenticated against the
+	// named cloud. If the cloud is not defined or authentication fails,
+	// this returns an error.
+	//
+	// The client verifies TLS certificates according to the cloud’s
+	// verify and cacert fields, and presents the client certificate given
+	// by its cert and key fields. If the server’s certificate is issued for
+	// a different host than auth_url names, as behind some load balancers,
+	// tls_server_name sets the name to verify it against instead.
+	// Setting insecure: true is the same as verify: false, and cacert may hold
+	// PEM certificates inline instead of naming a file (see TLSConfig).
+	// Alternatively, or as well, capath names a directory of trusted CA
+	// certificates, as some systems distribute them.
+	// A cloud’s headers field, a mapping of header names to values, adds
+	// headers to every request, such as a key required by an API gateway.
+	// ClientOptions adjust this call only.
+	//
+	// Relative cacert, capath, cert, and key paths are taken relative to the
+	// directory of the file the cloud was loaded from, as OpenStack’s own
+	// tools do. For content not loaded from a file, such as by FromBytes,
+	// and for paths set by environment variables, they are taken relative
+	// to the working directory.
+	//
+	// If the Config was loaded with WithClientCache, this may return a
+	// cached client rather than authenticating again.
+	Authenticate(name string, opts ...ClientOption) (*gophercloud.ProviderClient, error)
+
+	// ClientV is like Authenticate, but also returns the identity API
+	// version that was used, "2" or "3", so callers can choose matching
+	// service client constructors. This returns an error if the cloud
+	// declares an identity_api_version other than 2 or 3.
+	ClientV(name string, opts ...ClientOption) (*gophercloud.ProviderClient, string, error)
+
+	// VerifyAll authenticates against every cloud concurrently and returns
+	// the result for each, keyed by name: nil on success, or the error that
+	// made authentication fail. It never uses cached clients.
+	//
+	// At most a few clouds are checked at once. Cancelling ctx aborts the
+	// checks in flight, and clouds not yet checked report ctx.Err().
+	VerifyAll(ctx context.Context) map[string]error
+
+	// ProbeEndpoint checks that the named cloud’s auth_url can be reached,
+	// without authenticating: it resolves the host, opens a TCP connection,
+	// and, for an https URL, completes a TLS handshake using the cloud’s
+	// TLS settings. This catches DNS, network, and certificate problems
+	// before a full authentication is attempted. The probe connects
+	// directly, ignoring any proxy, and gives up when ctx is done.
+	//
+	// A failed step yields a *ProbeError whose Stage tells which one
+	// failed. If the cloud is not defined or its auth_url or TLS settings
+	// are invalid, this returns an ordinary error.
+	ProbeEndpoint(ctx context.Context, name string) error
+
+	// TokenScope authenticates against the named cloud and returns what
+	// the issued token was scoped to: its project or domain, its user, and
+	// its roles. This lets a tool confirm that a credential lands in the
+	// expected project with the expected roles. Requests are bound to ctx,
+	// and a cached client is never used. If the cloud is not defined,
+	// authentication fails, or the token carries no scope details, as with
+	// admin_token, this returns an error.
+	TokenScope(ctx context.Context, name string, opts ...ClientOption) (ScopeInfo, error)
+
+	// InvalidateClient discards any ProviderClient and domain IDs cached
+	// for the named cloud (see WithClientCache), so the next call
+	// authenticates afresh.
+	InvalidateClient(name string)
+
+	// CachedTokenValid reports whether the client cached for the named
+	// cloud (see WithClientCache) would be reused by the next call, along
+	// with its token’s remaining lifetime, without authenticating. A
+	// cached token is not valid once it is within a few minutes of
+	// expiring or the cloud’s config has changed. If there is no cached
+	// client, this reports false and a zero duration rather than an error;
+	// it returns an error only if the cloud is not defined.
+	CachedTokenValid(name string) (bool, time.Duration, error)
+
+	// TokenInfo authenticates against the named cloud and returns the
+	// time at which the issued token expires. Long-running callers can use
+	// this to plan re-authentication ahead of time.
+	TokenInfo(name string, opts ...ClientOption) (time.Time, error)
+
+	// ServiceClient returns a client for one service of the named cloud,
+	// authenticating against the cloud first. The service is one of
+	// compute, identity, image, network, or volume (or its alias,
+	// block-storage).
+	//
+	// The endpoint is selected by the cloud’s region_name and interface,
+	// unless overridden for the call with WithEndpointOpts. If interface
+	// lists several interfaces, each is tried in turn until the catalog
+	// has an endpoint for it (see InterfacePreference).
+	// The service’s API version comes from its <service>_api_version field,
+	// such as compute_api_version: "2.79". For compute and volume, a
+	// version with a minor part pins that microversion in the returned
+	// client’s Microversion field; otherwise the server default is used.
+	ServiceClient(name, service string, opts ...ClientOption) (*gophercloud.ServiceClient, error)
+
+	// RetryPolicy returns the retry policy declared for the named cloud.
+	// If the cloud declares none, this returns the zero RetryPolicy, which
+	// means no retries; if the cloud is not defined, it returns an error.
+	RetryPolicy(name string) (RetryPolicy, error)
+
+	// Merge copies every cloud from other into this Config, replacing any
+	// cloud of the same name, and returns how many clouds were added and
+	// how many were replaced. The merge is atomic: concurrent callers see
+	// either none or all of its changes.
+	Merge(other Config) (added, replaced int)
+
+	// Filter returns a new Config holding only the clouds, including
+	// disabled ones, for which pred returns true. The result is an
+	// independent snapshot: later changes to either Config, such as by
+	// Merge, do not affect the other. It keeps this Config’s default_cloud
+	// and load options, starts with an empty client cache if client
+	// caching is on, and has no Raw document.
+	Filter(pred func(name string, opts gophercloud.AuthOptions) bool) Config
+
+	// Only returns a new Config holding only the named cloud, which is
+	// also its default cloud, for handing one cloud to a subsystem. Like
+	// Filter’s, the result is an independent snapshot with the same load
+	// options and no Raw document. If the cloud is not defined, this
+	// returns an error.
+	Only(name string) (Config, error)
+
+	// Add defines a new cloud with the given options. If a cloud of the
+	// same name already exists, this returns an error; use Merge to
+	// replace one.
+	Add(name string, opts gophercloud.AuthOptions) error
+
+	// Remove deletes the named cloud, including a disabled one. If the
+	// cloud is not defined, this returns an error.
+	Remove(name string) error
+
+	// Snapshot returns a point-in-time copy of this Config’s clouds and
+	// default_cloud, unaffected by later changes such as Add, Remove, and
+	// Merge, for undoing a series of edits with Restore.
+	Snapshot() Snapshot
+
+	// Restore reinstates the clouds and default_cloud recorded by a
+	// Snapshot, discarding every change made since. The Snapshot is left
+	// unchanged, so it may be restored again.
+	Restore(s Snapshot)
+
+	// Raw returns a copy of the whole document this Config was loaded
+	// from, as decoded before being narrowed to the fields this package
+	// understands. Its shape mirrors the YAML exactly, with every mapping
+	// represented as a map[string]interface{}; values are as written, before
+	// any environment expansion. Callers may modify the result freely.
+	//
+	// Raw reflects the document as loaded and is unaffected by Merge. For
+	// a Config not loaded from a document, such as one from FromMap, this
+	// returns nil.
+	Raw() map[string]interface{}
+
+	// Extra returns a copy of the fields of the named cloud’s entry that
+	// this package does not understand, keyed by name, such as a
+	// vendor-specific block like rackspace: alongside auth. Unlike
+	// AuthBlock, this reflects every source merged into the entry, such
+	// as secure.yaml, and ExportCloud re-emits these fields. This returns
+	// nil if there are none, or an error if the cloud is not defined.
+	Extra(name string) (map[string]interface{}, error)
+
+	// AuthBlock returns a copy of the named cloud’s auth block exactly as
+	// written in the document Raw returns, including fields this package
+	// does not understand, for forwarding to other systems. Nothing is
+	// resolved or expanded. This returns nil if the cloud has no auth
+	// block or the Config was not loaded from a document, or an error if
+	// the cloud is not defined.
+	AuthBlock(name string) (map[string]interface{}, error)
+
+	// ExportCloud returns the named cloud as a standalone clouds.yaml
+	// document defining only that cloud. If the cloud is not defined, this
+	// returns an error.
+	ExportCloud(name string) ([]byte, error)
+
+	// ExportCloudRedacted is like ExportCloud, but masks secrets such as
+	// the password so the output is safe to share.
+	ExportCloudRedacted(name string) ([]byte, error)
+
+	// ExportEnv returns the OS_* environment variables that describe the
+	// named cloud to tools that read no clouds.yaml, such as OS_AUTH_URL,
+	// OS_USERNAME, OS_PROJECT_NAME, OS_USER_DOMAIN_NAME, and
+	// OS_REGION_NAME, keyed by name. Only fields the cloud sets are
+	// included, and only those WithEnvOverlay reads back, with a list of
+	// interfaces reduced to the first. Secrets are included; IsSecretEnvVar
+	// identifies them for output that should leave them out. If the cloud
+	// is not defined, this returns an error.
+	ExportEnv(name string) (map[string]string, error)
+
+	// ExportRC renders the variables ExportEnv returns as a shell script
+	// of export lines, sorted, for sourcing as an OpenStack RC file.
+	// Values are quoted for a POSIX shell, so spaces and special
+	// characters survive. Unless secrets is set, variables holding
+	// secrets, such as OS_PASSWORD, are left out, so the script is safe to
+	// share. If the cloud is not defined, this returns an error.
+	ExportRC(name string, secrets bool) ([]byte, error)
+
+	// FormatTable writes a table of the enabled clouds to w, sorted by
+	// name, with aligned columns for each cloud’s name, auth_url, region,
+	// interface, and auth type, for commands that list or diagnose
+	// clouds. Empty values are shown as a dash. Only those fields are
+	// shown, so secrets never appear; a password embedded in an auth_url
+	// is masked. This returns any error from writing to w.
+	FormatTable(w io.Writer) error
+
+	// Fingerprint returns a stable hash of the named cloud’s effective
+	// config, as a hex-encoded SHA-256 digest, for cache keys and change
+	// detection: it changes exactly when a setting ExportCloud would emit
+	// changes. Secrets contribute to it, so rotating a password changes the
+	// fingerprint, but cannot be read back from it. The cloud’s name does
+	// not contribute, so identical clouds share a fingerprint.
+	Fingerprint(name string) (string, error)
+
+	// AuthMethods returns the auth_methods the named cloud lists for a
+	// multi-factor auth_type, such as v3multifactor with [v3password,
+	// v3totp], or nil if it lists none. The options Get returns already
+	// carry each method’s credentials, such as the TOTP passcode, and
+	// gophercloud combines the methods they call for into one token
+	// request; this lets callers check or display which methods are
+	// expected. If the cloud is not defined, this returns an error.
+	AuthMethods(name string) ([]string, error)
+
+	// TLSConfig returns the TLS configuration for connecting to the named
+	// cloud, as Authenticate uses it: RootCAs from cacert, which may be a
+	// file or inline PEM, and from every PEM file in the capath directory,
+	// skipping files that hold no certificates, InsecureSkipVerify from
+	// verify: false or insecure: true, Certificates from cert and key, and
+	// ServerName from tls_server_name. Relative paths are taken relative to
+	// the directory of the file that set them. This returns nil, nil if the
+	// cloud needs no TLS customization, so the default transport can be
+	// used as-is. If the cloud is not defined, or a file cannot be loaded,
+	// this returns an error.
+	TLSConfig(name string) (*tls.Config, error)
+
+	// Validate checks that the named cloud sets every field its auth type
+	// requires (see RequiredFields). If the cloud is not defined, uses an
+	// unsupported auth_type, or is missing fields, this returns an error.
+	Validate(name string) error
+
+	// Invalid validates every enabled cloud, as Validate does, and returns
+	// the errors of those that fail, keyed by cloud name, or nil if none
+	// does. The result is the same for the same clouds, whatever the order
+	// in which they are checked.
+	Invalid() map[string]error
+
+	// CheckFiles checks that the cacert, capath, cert, and key files the named
+	// cloud references exist and can be read, so that a misplaced
+	// certificate is reported at load time rather than when connecting.
+	// Relative paths are taken relative to the directory of the clouds.yaml
+	// file, and inline PEM data is not checked. If the cloud is not
+	// defined, or any of its files cannot be read, this returns an error
+	// listing all of them.
+	CheckFiles(name string) error
+
+	// ValidateWith is like Validate, but checks the given required fields
+	// instead of the defaults for the cloud’s auth type.
+	//
+	// Fields are named as in clouds.yaml: auth_type, auth_url,
+	// region_name, username, user_id, password, project_name,
+	// project_id, tenant_name, tenant_id, domain_name, domain_id,
+	// user_domain_name, user_domain_id, project_domain_name,
+	// project_domain_id, token, endpoint, passcode,
+	// application_credential_id, application_credential_name, and
+	// application_credential_secret.
+	// Alternatives are separated by "|"; a requirement such as
+	// "username|user_id" is met if any of them is set.
+	ValidateWith(name string, required []string) error
+
+	// EffectiveCloud is like Get, but also reports, for each field the
+	// named cloud sets, the layer whose value is in effect: the file, a
+	// secret file, an indirection such as password_env, the environment,
+	// or a transform. This is meant for explaining where a surprising
+	// value came from. Secrets are reported by field name only. A Config
+	// not loaded from a document, such as one from FromMap, reports no
+	// sources.
+	EffectiveCloud(name string) (gophercloud.AuthOptions, []Source, error)
+
+	// SetFields returns the names of the fields the named cloud sets,
+	// sorted, without their values: the fields ExportCloud writes for it,
+	// named as in region_name, headers, or auth.user_domain_name.
+	// Equivalent fields are reported under the name ExportCloud uses, such
+	// as verify for insecure: true. This suits audits, such as spotting
+	// clouds that set no domain, without exposing secrets. If the cloud is
+	// not defined, this returns an error.
+	SetFields(name string) ([]string, error)
+
+	// InterfacePreference returns the catalog interfaces the named cloud
+	// accepts, in order of preference. A cloud’s interface may be one name,
+	// such as internal, or a list, such as [internal, public] to prefer
+	// internal endpoints but fall back to public ones. This returns nil if
+	// the cloud names no interface, or an error if it is not defined or
+	// names an unsupported interface.
+	InterfacePreference(name string) ([]gophercloud.Availability, error)
+
+	// EndpointOpts returns the options for selecting the named cloud’s
+	// service endpoints in a region from the catalog, as ServiceClient
+	// uses them: the region, or the cloud’s region_name if region is
+	// empty, and the cloud’s preferred interface there. An entry in the
+	// regions list may set its own interface, as in
+	//
+	//	regions:
+	//	- name: home
+	//	  values:
+	//	    interface: internal
+	//	- away
+	//
+	// and the cloud-wide interface applies to regions that set none. If
+	// the cloud is not defined or names an unsupported interface, this
+	// returns an error.
+	EndpointOpts(name, region string) (gophercloud.EndpointOpts, error)
+
+	// ClientConfig returns the two things needed to start making API
+	// calls to the named cloud: its auth options, as Get returns them, and
+	// its endpoint options in its own region, as EndpointOpts returns
+	// them. Both come from the same entry, so they cannot disagree even if
+	// the Config changes between calls. If the cloud is not defined or
+	// names an unsupported interface, this returns an error.
+	ClientConfig(name string) (gophercloud.AuthOptions, gophercloud.EndpointOpts, error)
+
+	// AllRegions returns the regions of every cloud, keyed by cloud name
+	// and sorted. A cloud’s regions are those named by its region_name and
+	// its regions list, whose entries may be names or mappings with a name
+	// field. Clouds that define no regions are omitted; if none do, this
+	// returns nil.
+	AllRegions() map[string][]string
+
+	// SameEndpoint reports whether two clouds authenticate against the
+	// same Keystone in the same region. Their auth_url values are compared
+	// once normalized, so differences of scheme, default port, host case,
+	// or trailing slash do not matter. Clouds without an auth_url never
+	// match. If either cloud is not defined, this returns an error.
+	SameEndpoint(a, b string) (bool, error)
+
+	// GroupByEndpoint returns the names of the clouds, sorted, keyed by the
+	// normalized auth_url they share (see SameEndpoint), such as
+	// keystone.example.com:5000/v3. Regions are not considered, and clouds
+	// without an auth_url are omitted. If there are none, this returns nil.
+	GroupByEndpoint() map[string][]string
+
+	// EnvOverrides returns the fields of the named cloud that were set
+	// from OS_* environment variables, with the variable each came from,
+	// so automation can log which settings the environment overrode
+	// without logging their values. This is empty unless the Config was
+	// loaded with WithEnvOverlay and the cloud is the one selected.
+	EnvOverrides(name string) ([]EnvOverride, error)
+
+	// Description returns the human-readable description of the named
+	// cloud, given by its description field, for display in place of the
+	// cloud’s name. This returns an empty string if the cloud has no
+	// description, or an error if the cloud is not defined.
+	Description(name string) (string, error)
+
+	// SchemaKind reports which generation of clouds.yaml conventions the
+	// named cloud’s entry follows, judging by the auth fields it sets:
+	// SchemaV2 for tenant_name or tenant_id, SchemaV3 for project_name,
+	// project_id, or a domain field, SchemaMixed for both, and
+	// SchemaUnknown for neither, as with application credentials. This is
+	// informational, for flagging legacy entries for migration, and does
+	// not change how the entry is parsed. If the cloud is not defined,
+	// this returns an error.
+	SchemaKind(name string) (string, error)
 }
 
 // configImpl implements the Config interface.
 type configImpl struct {
-	clouds map[string]gophercloud.AuthOptions
+	mu           sync.RWMutex
+	clouds       map[string]cloud
+	defaultCloud string
+	src          []byte
+	order        *cloudOrder
+	cloudsKey    string
+	cache        *clientCache
+	reloadTokens bool
+	prompt       func(cloud, field string) (string, error)
+	providers    []CredentialProvider
+}
+
+// cloud holds the configuration parsed for one cloud.
+type cloud struct {
+	auth         gophercloud.AuthOptions
+	authType     string
+	region       string
+	regions      []string
+	description  string
+	disabled     bool
+	ifaces       []string
+	regionIfaces map[string][]string
+	apiVersions  map[string]string
+	tls          tlsSettings
+	retry        RetryPolicy
+	tokenFile    string
+	endpoint     string
+	headers      map[string]string
+	secretHdrs   map[string]bool
+	authMethods  []string
+	projDomain   projectDomain
+	extra        map[string]interface{}
+	sources      provenance
+	envOverrides []EnvOverride
+	schemaKind   string
 }
 
 // Get satisfies the Config interface.
 func (c *configImpl) Get(name string) (gophercloud.AuthOptions, error) {
-	if v, ok := c.clouds[name]; ok {
+	v, err := c.cloudWithSecrets(name)
+	if err != nil {
+		return gophercloud.AuthOptions{}, err
+	}
+	return cloneAuthOptions(v.auth), nil
+}
+
+// DefaultName satisfies the Config interface.
+func (c *configImpl) DefaultName() (string, error) {
+	c.mu.RLock()
+	defaultCloud := c.defaultCloud
+	c.mu.RUnlock()
+	name := selectDefault(c.Names(), defaultCloud)
+	if name == "" {
+		return "", errors.New("config: no default cloud; set OS_CLOUD to choose one")
+	}
+	if _, err := c.cloud(name); err != nil {
+		return "", err
+	}
+	return name, nil
+}
+
+// Default satisfies the Config interface.
+func (c *configImpl) Default() (gophercloud.AuthOptions, error) {
+	name, err := c.DefaultName()
+	if err != nil {
+		return gophercloud.AuthOptions{}, err
+	}
+	return c.Get(name)
+}
+
+// selectDefault returns the name of the default cloud among names, as
+// documented by DefaultName, or an empty string if there is none. The name is
+// not checked against names.
+func selectDefault(names []string, defaultCloud string) string {
+	name := firstNonEmpty(os.Getenv("OS_CLOUD"), os.Getenv("OS_CLOUD_NAME"), defaultCloud)
+	if name == "" && len(names) == 1 {
+		name = names[0]
+	}
+	return name
+}
+
+// Description satisfies the Config interface.
+func (c *configImpl) Description(name string) (string, error) {
+	v, err := c.cloud(name)
+	if err != nil {
+		return "", err
+	}
+	return v.description, nil
+}
+
+// cloud returns the named cloud, or an error if it is not defined.
+func (c *configImpl) cloud(name string) (cloud, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if v, ok := c.clouds[name]; ok && !v.disabled {
+		if c.reloadTokens {
+			return v.reloadToken(name)
+		}
 		return v, nil
 	}
-	err := errors.New("config: cloud `" + name + "` not found")
-	return gophercloud.AuthOptions{}, err
+	return cloud{}, errors.New("config: cloud `" + name + "` not found")
 }
 
 // GetAll satisfies the Config interface.
 func (c *configImpl) GetAll() map[string]gophercloud.AuthOptions {
-	if len(c.clouds) == 0 {
+	return c.all(false)
+}
+
+// AllIncludingDisabled satisfies the Config interface.
+func (c *configImpl) AllIncludingDisabled() map[string]gophercloud.AuthOptions {
+	return c.all(true)
+}
+
+// all returns copies of the options for every cloud, including disabled
+// clouds only if asked. If there are none, this returns nil.
+func (c *configImpl) all(disabled bool) map[string]gophercloud.AuthOptions {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	cs := map[string]gophercloud.AuthOptions{}
+	for k, v := range c.clouds {
+		if !v.disabled || disabled {
+			cs[k] = cloneAuthOptions(v.auth)
+		}
+	}
+	if len(cs) == 0 {
 		return nil
 	}
-	cs := map[string]gophercloud.AuthOptions{}
+	return cs
+}
+
+// Names satisfies the Config interface.
+func (c *configImpl) Names() []string {
+	return c.names(func(cloud) bool { return true })
+}
+
+// NamesByAuthType satisfies the Config interface.
+func (c *configImpl) NamesByAuthType(t string) []string {
+	return c.names(func(v cloud) bool { return v.effectiveAuthType() == t })
+}
+
+// names returns the sorted names of the enabled clouds for which keep returns
+// true. If there are none, this returns nil.
+func (c *configImpl) names(keep func(cloud) bool) []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	var names []string
 	for k, v := range c.clouds {
-		cs[k] = v
+		if !v.disabled && keep(v) {
+			names = append(names, k)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// AllRedacted satisfies the Config interface.
+func (c *configImpl) AllRedacted() map[string]gophercloud.AuthOptions {
+	cs := c.GetAll()
+	for k, v := range cs {
+		cs[k] = redact(v)
 	}
 	return cs
 }
 
+// cloneAuthOptions returns a copy of a that shares no memory with it.
+func cloneAuthOptions(a gophercloud.AuthOptions) gophercloud.AuthOptions {
+	if a.Scope != nil {
+		scope := *a.Scope
+		a.Scope = &scope
+	}
+	return a
+}
+
 // New returns an initialized *Config.
 //
-// This searches for a clouds.yaml file in the following directories:
+// This searches for a clouds.yaml file in the following locations:
 //
-//        1) current directory
-//        2) ~/.config/openstack
-//        3) /etc/openstack
+//  1. the file named by $OS_CLIENT_CONFIG_FILE, if set
+//  2. current directory
+//  3. the directory named by $OS_CONFIG_DIR, if set
+//  4. ~/.config/openstack (see WithUserConfigDir), skipped if the home
+//     directory cannot be found
+//  5. /etc/openstack
 //
 // The first valid clouds.yaml file found wins. (See the documentation at
-// http://docs.openstack.org/developer/os-client-config/)
+// http://docs.openstack.org/developer/os-client-config/) A location with no
+// clouds.yaml file, such as an $OS_CONFIG_DIR without one, is skipped.
 //
-// New returns an error if a suitable clouds.yaml file is not found.
+// New returns an error if a suitable clouds.yaml file is not found. Files that
+// are empty, or contain only comments and whitespace, are skipped; any other
+// malformed file stops the search with a *ParseError, and a file that exists
+// but cannot be read for lack of permission, which is almost certainly a
+// mistake, stops it with a *FileError. A file whose clouds are all disabled,
+// or define no credentials, is usable and yields a Config with no enabled
+// clouds, unless WithAllDisabledAsEmpty makes it count as empty and be
+// skipped. With WithStrictDiscovery, New also returns an error, listing the
+// files, if more than one location holds a usable clouds.yaml file.
 //
 // To specify a file directly rather than searching known paths, use FromFile.
-func New() (Config, error) {
-	paths, err := getDefaultPaths()
+// To also learn which file was chosen, use NewVerbose.
+func New(opts ...Option) (Config, error) {
+	conf, _, err := NewVerbose(opts...)
+	return conf, err
+}
+
+// NewVerbose is like New, but also returns the path of the clouds.yaml file
+// it loaded, for callers that log or later operate on the selected file.
+func NewVerbose(opts ...Option) (Config, string, error) {
+	o, err := newOptions(opts)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
+	return newVerbose(o)
+}
+
+// newVerbose implements NewVerbose with options already applied.
+func newVerbose(o *options) (Config, string, error) {
+	paths, err := searchPaths(o)
+	if err != nil {
+		return nil, "", err
+	}
+	var (
+		found []string
+		first Config
+	)
 	for _, p := range paths {
-		conf, err := FromFile(p)
+		conf, err := fromFile(p, o)
 		if err == nil {
-			return conf, nil
+			if !o.strictDiscovery {
+				return conf, p, nil
+			}
+			if first == nil {
+				first = conf
+			}
+			found = append(found, p)
+			continue
+		}
+		// Return an error if cloud.yaml is not well-formed or cannot be
+		// read; otherwise, just continue to the next file.
+		if parseErr, ok := err.(*ParseError); ok && parseErr.Err != ErrEmptyConfig {
+			return nil, "", parseErr
 		}
-		// Return an error if cloud.yaml is not well-formed; otherwise,
-		// just continue to the next file.
-		if parseErr, ok := err.(*ParseError); ok {
-			return nil, parseErr
+		if errors.Is(err, os.ErrPermission) {
+			return nil, "", err
 		}
 	}
-	return nil, errors.New("config: no usable clouds.yaml file found")
+	switch len(found) {
+	case 0:
+		return nil, "", errNoConfigFile
+	case 1:
+		return first, found[0], nil
+	}
+	s := "config: more than one clouds.yaml file found: " + strings.Join(found, ", ")
+	return nil, "", errors.New(s)
 }
 
 // FromFile returns an initialized *Config from a given clouds.yaml file. This
-// returns an error if the file cannot be read or is in an invalid format.
-func FromFile(path string) (Config, error) {
-	b, err := ioutil.ReadFile(path)
+// returns a *FileError if the file cannot be opened, or a *ParseError if it is
+// in an invalid format. A file that defines no clouds yields a *ParseError
+// wrapping ErrEmptyConfig.
+//
+// If a key appears more than once in the same mapping, the last occurrence
+// wins, as in the YAML decoder. WithRejectDuplicateKeys makes this an error.
+//
+// Content should be UTF-8. UTF-16 content with a byte order mark, as written
+// by some Windows tools, is transcoded first; other content that is not UTF-8
+// yields a *ParseError saying so.
+//
+// Gzip-compressed files, such as clouds.yaml.gz, are detected by their
+// content and decompressed transparently. A corrupt compressed file yields a
+// *ParseError.
+//
+// Credentials belong in each cloud’s auth block. For compatibility with older
+// files, if a cloud has no auth block, auth fields such as username and
+// auth_url placed directly in the cloud entry are used instead. The identity
+// v2 names tenant_name and tenant_id are accepted as aliases of project_name
+// and project_id; FromFile returns a *ParseError if a cloud sets both forms
+// of one to different values.
+//
+// Auth fields shared by several clouds may be kept once, in a named block
+// under the top-level auth_templates key, which each cloud names with
+// auth_ref:
+//
+//	auth_templates:
+//	  corp:
+//	    auth_url: https://keystone.example.com:5000/v3
+//	    user_domain_name: corp
+//	clouds:
+//	  prod:
+//	    auth_ref: corp
+//	    auth:
+//	      project_name: prod
+//
+// The template’s fields are used where the cloud’s own auth fields do not set
+// them. FromFile returns a *ParseError if a cloud names a template that is
+// not defined.
+//
+// An auth block may name the environment variable holding its password with
+// password_env instead of including the password itself:
+//
+//	auth:
+//	  username: admin
+//	  password_env: OS_PASSWORD_PROD
+//
+// The variable is read when the file is loaded, and its value takes
+// precedence over any password in the file. FromFile returns a *ParseError if
+// a referenced variable is not set.
+//
+// A password, token, or application_credential_secret may also be a file URI,
+// as used by systems that mount secrets as files:
+//
+//	auth:
+//	  password: file:///run/secrets/os_password
+//
+// The file is read when the file is loaded, and its content, without trailing
+// whitespace, is used in place of the URI. FromFile returns a *ParseError
+// naming the field if the file cannot be read.
+//
+// Similarly, token_file names a file holding the token, such as one that is
+// refreshed out of band. Its content, without surrounding whitespace, takes
+// precedence over any token in the file. It is read when the file is loaded,
+// or on every lookup with WithTokenFileReload. A missing token_file leaves the
+// token unset, which Validate reports for clouds that need one.
+//
+// An application credential’s secret may instead be kept in the system
+// keyring (the macOS Keychain, Windows Credential Manager, or a Secret Service
+// such as GNOME Keyring), named by keyring_service and keyring_account:
+//
+//	auth:
+//	  application_credential_id: 21dced0fd20347869b93710d2b98aae0
+//	  keyring_service: openstack
+//	  keyring_account: prod
+//
+// The account defaults to the cloud’s name. The secret is fetched when the
+// file is loaded and takes precedence over any in the file. FromFile returns
+// a *ParseError if the keyring has no such entry. Keyring support is only
+// built with the keyring build tag; without it, a cloud naming a keyring
+// entry yields a *ParseError.
+//
+// A cloud with auth_type admin_token skips the identity service entirely: its
+// token, obtained elsewhere, is sent as it is to the service endpoint named by
+// endpoint, and no catalog is read:
+//
+//	auth_type: admin_token
+//	auth:
+//	  endpoint: https://compute.example.com:8774/v2.1
+//	  token: gAAAAABk...
+//
+// This suits automation handed a token by a broker, but gives up what the
+// identity service provides. The token cannot be renewed, so requests fail
+// once it expires. The endpoint is trusted as given, not taken from a catalog,
+// so it must be verified by other means, and every client built for the cloud,
+// whatever its service, talks to that one endpoint. Authenticate returns an
+// error if either field is missing.
+//
+// Alternatively, password_command gives a shell command that prints the
+// password, such as one reading it from a password manager. The command runs
+// when the file is loaded and is killed if it takes longer than the timeout
+// set by WithPasswordCommandTimeout. FromFile returns a *ParseError if the
+// command fails or times out, or if an auth block sets both password_env and
+// password_command.
+func FromFile(path string, opts ...Option) (Config, error) {
+	o, err := newOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+	return fromFile(path, o)
+}
+
+// fromFile implements FromFile with options already applied.
+func fromFile(path string, o *options) (Config, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, &FileError{path, err}
+	}
+	defer f.Close()
+	if o.secureFile {
+		return fromFileWithSecure(f, path, o)
+	}
+	return fromReader(f, path, o)
+}
+
+// FromReader returns an initialized *Config from clouds.yaml content read
+// from r. Like FromFile, this transparently decompresses gzip content.
+func FromReader(r io.Reader, opts ...Option) (Config, error) {
+	o, err := newOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+	return fromReader(r, "", o)
+}
+
+// fromReader reads and parses clouds.yaml content, decompressing it first if
+// it begins with the gzip magic number. The path is used only to identify the
+// source in errors.
+func fromReader(r io.Reader, path string, o *options) (Config, error) {
+	b, err := readAll(r, path, o)
+	if err != nil {
+		return nil, err
+	}
+	return parse(b, path, o)
+}
+
+// readAll reads content from r, decompressing it first if it begins with the
+// gzip magic number. The path is used only to identify the source in errors.
+// Content larger than the maximum size is rejected with a *ParseError, before
+// more than that is read.
+func readAll(r io.Reader, path string, o *options) ([]byte, error) {
+	br := bufio.NewReader(r)
+	src := io.Reader(br)
+	gzipped := false
+	if magic, err := br.Peek(2); err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		zr, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, &ParseError{path, errors.New("cannot decompress: " + err.Error())}
+		}
+		defer zr.Close()
+		src = zr
+		gzipped = true
+	}
+	b, err := ioutil.ReadAll(io.LimitReader(src, o.maxSize+1))
 	if err != nil {
+		if gzipped {
+			return nil, &ParseError{path, errors.New("cannot decompress: " + err.Error())}
+		}
 		return nil, errors.New("config: " + err.Error())
 	}
+	if int64(len(b)) > o.maxSize {
+		s := "content exceeds the maximum size of " + strconv.FormatInt(o.maxSize, 10) + " bytes"
+		return nil, &ParseError{path, errors.New(s)}
+	}
+	return b, nil
+}
+
+// FromBytes returns an initialized *Config from clouds.yaml content already
+// in memory. This returns a *ParseError if the content is in an invalid format.
+func FromBytes(b []byte, opts ...Option) (Config, error) {
+	o, err := newOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+	return parse(b, "", o)
+}
+
+// FromBytesNamed is like FromBytes, but identifies the content by name, such
+// as "embedded:default.yaml" for a file bundled with go:embed, in place of a
+// file path in any *ParseError. The name is only a label: relative file paths
+// in the content are still taken relative to the working directory.
+func FromBytesNamed(b []byte, name string, opts ...Option) (Config, error) {
+	o, err := newOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+	return parseWithSecure(b, name, nil, "", "", o)
+}
+
+// cloudYAML is a cloud entry in clouds.yaml.
+type cloudYAML struct {
+	Profile     string        `yaml:"profile,omitempty"`
+	AuthRef     string        `yaml:"auth_ref,omitempty"`
+	Auth        *authYAML     `yaml:"auth,omitempty"`
+	AuthType    string        `yaml:"auth_type,omitempty"`
+	RegionName  string        `yaml:"region_name,omitempty"`
+	Regions     []regionYAML  `yaml:"regions,omitempty"`
+	Description string        `yaml:"description,omitempty"`
+	Disabled    bool          `yaml:"disabled,omitempty"`
+	Interface   interfaceList `yaml:"interface,omitempty"`
+	Verify      *bool         `yaml:"verify,omitempty"`
+	Insecure    *bool         `yaml:"insecure,omitempty"`
+	CACert      string        `yaml:"cacert,omitempty"`
+	CAPath      string        `yaml:"capath,omitempty"`
+	Cert        string        `yaml:"cert,omitempty"`
+	Key         string        `yaml:"key,omitempty"`
+	ServerName  string        `yaml:"tls_server_name,omitempty"`
+
+	ConnectRetries    int     `yaml:"connect_retries,omitempty"`
+	ReadRetries       int     `yaml:"read_retries,omitempty"`
+	ConnectRetryDelay float64 `yaml:"connect_retry_delay,omitempty"`
+
+	ComputeAPIVersion      string `yaml:"compute_api_version,omitempty"`
+	IdentityAPIVersion     string `yaml:"identity_api_version,omitempty"`
+	ImageAPIVersion        string `yaml:"image_api_version,omitempty"`
+	NetworkAPIVersion      string `yaml:"network_api_version,omitempty"`
+	VolumeAPIVersion       string `yaml:"volume_api_version,omitempty"`
+	BlockStorageAPIVersion string `yaml:"block_storage_api_version,omitempty"`
+
+	// RegionAliases maps symbolic region names, such as home or dr, to the
+	// regions they stand for.
+	RegionAliases map[string]string `yaml:"region_aliases,omitempty"`
+
+	// Headers holds HTTP headers to send with every request to the cloud,
+	// such as a key required by an API gateway.
+	Headers map[string]string `yaml:"headers,omitempty"`
+
+	// Flat holds auth fields placed directly in the cloud entry, as some
+	// older files do, for use when the entry has no auth block.
+	Flat authYAML `yaml:",inline"`
+
+	// Extra holds the fields of the cloud entry this package does not
+	// understand, such as vendor-specific blocks, so they survive export.
+	Extra map[string]interface{} `yaml:",inline"`
+}
+
+// apiVersions returns the cloud entry’s API versions keyed by service, as used
+// by ServiceClient. block_storage_api_version is an alias for
+// volume_api_version and takes precedence over it.
+func (v *cloudYAML) apiVersions() map[string]string {
+	vs := map[string]string{}
+	for service, version := range map[string]string{
+		"compute":  v.ComputeAPIVersion,
+		"identity": v.IdentityAPIVersion,
+		"image":    v.ImageAPIVersion,
+		"network":  v.NetworkAPIVersion,
+		"volume":   firstNonEmpty(v.BlockStorageAPIVersion, v.VolumeAPIVersion),
+	} {
+		if version != "" {
+			vs[service] = version
+		}
+	}
+	return vs
+}
+
+// authYAML is the auth block of a cloud entry in clouds.yaml.
+type authYAML struct {
+	AuthURL                     string `yaml:"auth_url,omitempty"`
+	Username                    string `yaml:"username,omitempty"`
+	UserID                      string `yaml:"user_id,omitempty"`
+	Password                    string `yaml:"password,omitempty"`
+	PasswordEnv                 string `yaml:"password_env,omitempty"`
+	PasswordCommand             string `yaml:"password_command,omitempty"`
+	Passcode                    string `yaml:"passcode,omitempty"`
+	ProjectName                 string `yaml:"project_name,omitempty"`
+	ProjectID                   string `yaml:"project_id,omitempty"`
+	TenantName                  string `yaml:"tenant_name,omitempty"`
+	TenantID                    string `yaml:"tenant_id,omitempty"`
+	DomainName                  string `yaml:"domain_name,omitempty"`
+	DomainID                    string `yaml:"domain_id,omitempty"`
+	UserDomainName              string `yaml:"user_domain_name,omitempty"`
+	UserDomainID                string `yaml:"user_domain_id,omitempty"`
+	ProjectDomainName           string `yaml:"project_domain_name,omitempty"`
+	ProjectDomainID             string `yaml:"project_domain_id,omitempty"`
+	Token                       string `yaml:"token,omitempty"`
+	TokenFile                   string `yaml:"token_file,omitempty"`
+	Endpoint                    string `yaml:"endpoint,omitempty"`
+	ApplicationCredentialID     string `yaml:"application_credential_id,omitempty"`
+	ApplicationCredentialName   string `yaml:"application_credential_name,omitempty"`
+	ApplicationCredentialSecret string `yaml:"application_credential_secret,omitempty"`
+	KeyringService              string `yaml:"keyring_service,omitempty"`
+	KeyringAccount              string `yaml:"keyring_account,omitempty"`
+
+	// AuthMethods lists the methods of a multi-factor auth_type, such as
+	// v3password and v3totp.
+	AuthMethods []string `yaml:"auth_methods,omitempty"`
+}
+
+// empty reports whether an auth block sets no fields.
+func (a *authYAML) empty() bool {
+	return reflect.ValueOf(*a).IsZero()
+}
+
+// authOptions converts an auth block to gophercloud.AuthOptions.
+//
+// project_* and tenant_* are aliases, with project_* preferred. The user’s
+// domain comes from user_domain_* or, failing that, domain_*. A distinct
+// project domain is carried in the scope of a project named (not ID’d) scope;
+// resolve keeps it on the cloud as well, for a project given either way.
+func (a *authYAML) authOptions() gophercloud.AuthOptions {
+	opts := gophercloud.AuthOptions{
+		IdentityEndpoint:            a.AuthURL,
+		Username:                    a.Username,
+		UserID:                      a.UserID,
+		Password:                    a.Password,
+		Passcode:                    a.Passcode,
+		TenantID:                    firstNonEmpty(a.ProjectID, a.TenantID),
+		TenantName:                  firstNonEmpty(a.ProjectName, a.TenantName),
+		DomainID:                    firstNonEmpty(a.UserDomainID, a.DomainID),
+		DomainName:                  firstNonEmpty(a.UserDomainName, a.DomainName),
+		TokenID:                     a.Token,
+		ApplicationCredentialID:     a.ApplicationCredentialID,
+		ApplicationCredentialName:   a.ApplicationCredentialName,
+		ApplicationCredentialSecret: a.ApplicationCredentialSecret,
+	}
+	projectDomain := a.ProjectDomainID != "" || a.ProjectDomainName != ""
+	if opts.TenantID == "" && opts.TenantName != "" && projectDomain {
+		opts.Scope = &gophercloud.AuthScope{
+			ProjectName: opts.TenantName,
+			DomainID:    a.ProjectDomainID,
+			DomainName:  a.ProjectDomainName,
+		}
+	}
+	return opts
+}
+
+// newAuthYAML converts gophercloud.AuthOptions to an auth block, the inverse
+// of authOptions.
+func newAuthYAML(opts gophercloud.AuthOptions) *authYAML {
+	a := &authYAML{
+		AuthURL:                     opts.IdentityEndpoint,
+		Username:                    opts.Username,
+		UserID:                      opts.UserID,
+		Password:                    opts.Password,
+		Passcode:                    opts.Passcode,
+		ProjectName:                 opts.TenantName,
+		ProjectID:                   opts.TenantID,
+		UserDomainName:              opts.DomainName,
+		UserDomainID:                opts.DomainID,
+		Token:                       opts.TokenID,
+		ApplicationCredentialID:     opts.ApplicationCredentialID,
+		ApplicationCredentialName:   opts.ApplicationCredentialName,
+		ApplicationCredentialSecret: opts.ApplicationCredentialSecret,
+	}
+	if opts.Scope != nil {
+		a.ProjectDomainName = opts.Scope.DomainName
+		a.ProjectDomainID = opts.Scope.DomainID
+	}
+	return a
+}
+
+// firstNonEmpty returns the first of its arguments that is not empty.
+func firstNonEmpty(ss ...string) string {
+	for _, s := range ss {
+		if s != "" {
+			return s
+		}
+	}
+	return ""
+}
+
+// parse returns an initialized *Config from clouds.yaml content. The path is
+// used only to identify the source in a *ParseError.
+func parse(b []byte, path string, o *options) (Config, error) {
+	dir := ""
+	if path != "" {
+		dir = filepath.Dir(path)
+	}
+	return parseWithSecure(b, path, nil, "", dir, o)
+}
 
-	type authYAML struct {
-		Username   string `yaml:"username"`
-		Password   string `yaml:"password"`
-		TenantName string `yaml:"tenant_name"`
-		TenantID   string `yaml:"tenant_id"`
-		AuthURL    string `yaml:"auth_url"`
+// parseWithSecure is like parse, but merges secure.yaml content over the
+// clouds.yaml content first; a nil secure skips the merge. Relative file paths
+// in the content are taken relative to dir, if it is not empty.
+func parseWithSecure(b []byte, path string, secure []byte, securePath, dir string, o *options) (Config, error) {
+	b, y, defaultCloud, err := decodeDocument(b, path, o)
+	if err != nil {
+		return nil, err
+	}
+	var sy map[string]*cloudYAML
+	if secure != nil {
+		var secureDefault string
+		secure, sy, secureDefault, err = decodeDocument(secure, securePath, o)
+		if err != nil {
+			return nil, err
+		}
+		if secureDefault != "" {
+			defaultCloud = secureDefault
+		}
+		if y == nil {
+			y = map[string]*cloudYAML{}
+		}
+		for k := range sy {
+			if _, ok := y[k]; !ok {
+				y[k] = nil
+			}
+		}
+	}
+	if len(y) == 0 {
+		return nil, &ParseError{path, ErrEmptyConfig}
+	}
+
+	var enabled []string
+	for k, v := range y {
+		sv := sy[k]
+		if (v != nil || sv != nil) && !(v != nil && v.Disabled || sv != nil && sv.Disabled) {
+			enabled = append(enabled, k)
+		}
+	}
+	selected := ""
+	if o.envOverlay {
+		selected = firstNonEmpty(o.overlayCloud, selectDefault(enabled, defaultCloud))
+	}
+	var profiles map[string]*cloudYAML
+	for _, v := range y {
+		if v != nil && v.Profile != "" {
+			if profiles, err = loadProfiles(o); err != nil {
+				return nil, err
+			}
+			break
+		}
+	}
+	clouds := map[string]cloud{}
+	for k, v := range y {
+		var profile *cloudYAML
+		if v != nil && v.Profile != "" {
+			if profile = profiles[v.Profile]; profile == nil {
+				msg := "cloud `" + k + "`: profile `" + v.Profile + "` is not defined in " + publicCloudsFile
+				return nil, &ParseError{path, errors.New(msg)}
+			}
+		}
+		cl, ok, err := resolve(k, profile, v, sy[k], dir, o, k == selected)
+		if err != nil {
+			return nil, &ParseError{path, err}
+		}
+		if ok {
+			clouds[k] = cl
+		}
+	}
+	for k, v := range clouds {
+		for _, f := range o.transforms {
+			before := values(newAuthYAML(v.auth))
+			v.auth = f(k, cloneAuthOptions(v.auth))
+			v.sources.diff("auth.", before, values(newAuthYAML(v.auth)), LayerTransform)
+		}
+		clouds[k] = v
 	}
-	y := map[string]map[string]map[string]*authYAML{}
-	if err := yaml.Unmarshal(b, y); err != nil {
-		return nil, &ParseError{path, err}
+	if o.disabledAsEmpty && !anyEnabled(clouds) {
+		return nil, &ParseError{path, ErrEmptyConfig}
 	}
-	if len(y["clouds"]) == 0 {
-		return nil, &ParseError{path, errors.New("config is empty")}
+	if o.minIdentity != "" {
+		if err := checkIdentityVersions(clouds, o.minIdentity); err != nil {
+			return nil, &ParseError{path, err}
+		}
+	}
+	c := &configImpl{
+		clouds:       clouds,
+		defaultCloud: defaultCloud,
+		src:          b,
+		order:        &cloudOrder{cloudsKey: o.cloudsKey, docs: [][]byte{b, secure}},
+		cloudsKey:    o.cloudsKey,
+		reloadTokens: o.reloadTokens,
+		prompt:       o.prompt,
+		providers:    o.providers,
+	}
+	if o.cache {
+		c.cache = newClientCache()
 	}
+	return c, nil
+}
 
-	clouds := map[string]gophercloud.AuthOptions{}
-	for k, v := range y["clouds"] {
-		if a, ok := v["auth"]; ok {
-			clouds[k] = gophercloud.AuthOptions{
-				IdentityEndpoint: a.AuthURL,
-				Password:         a.Password,
-				TenantID:         a.TenantID,
-				TenantName:       a.TenantName,
-				Username:         a.Username,
+// decodeDocument decodes a clouds.yaml or secure.yaml document, returning its
+// content as UTF-8 along with its clouds and its default_cloud field. The path
+// is used only to identify the source in a *ParseError.
+func decodeDocument(b []byte, path string, o *options) ([]byte, map[string]*cloudYAML, string, error) {
+	b, err := toUTF8(b)
+	if err != nil {
+		return nil, nil, "", &ParseError{path, err}
+	}
+	if len(bytes.TrimSpace(b)) == 0 {
+		// Whitespace alone, including tabs, which YAML rejects, is an
+		// empty document rather than a malformed one.
+		return b, nil, "", nil
+	}
+	if b, err = toYAML(b, o); err != nil {
+		return nil, nil, "", &ParseError{path, err}
+	}
+	y, defaultCloud, err := decode(b, o)
+	if err != nil && o.format == FormatAuto {
+		if t, terr := tomlToYAML(b); terr == nil {
+			if ty, tdefault, terr := decode(t, o); terr == nil {
+				b, y, defaultCloud, err = t, ty, tdefault, nil
 			}
 		}
 	}
-	return &configImpl{clouds: clouds}, nil
+	if err != nil {
+		// Only check the encoding once parsing has failed, to explain
+		// what would otherwise be a baffling YAML error.
+		if !utf8.Valid(b) {
+			return nil, nil, "", &ParseError{path, errNotUTF8}
+		}
+		return nil, nil, "", &ParseError{path, err}
+	}
+	if o.noDups {
+		if err := checkDuplicateKeys(b, o.cloudsKey); err != nil {
+			return nil, nil, "", &ParseError{path, err}
+		}
+	}
+	if o.noUnknown {
+		if err := checkUnknownKeys(b, o.cloudsKey); err != nil {
+			return nil, nil, "", &ParseError{path, err}
+		}
+	}
+	return b, y, defaultCloud, nil
+}
+
+// decode decodes the clouds section of a document, along with its
+// default_cloud field, with the auth templates named by its entries applied.
+//
+// In the usual case, the document is decoded once, straight into the
+// clouds.yaml schema. A custom clouds key or environment expansion instead
+// needs the document decoded generically first; the clouds section is then
+// re-encoded and decoded into the schema, so other top-level keys may hold
+// anything.
+func decode(b []byte, o *options) (map[string]*cloudYAML, string, error) {
+	if o.cloudsKey == "clouds" && !o.expandEnv {
+		var doc struct {
+			Clouds        map[string]*cloudYAML `yaml:"clouds"`
+			DefaultCloud  string                `yaml:"default_cloud"`
+			AuthTemplates map[string]*authYAML  `yaml:"auth_templates"`
+		}
+		if err := yaml.Unmarshal(b, &doc); err != nil {
+			return nil, "", err
+		}
+		if err := applyAuthTemplates(doc.Clouds, doc.AuthTemplates); err != nil {
+			return nil, "", err
+		}
+		return doc.Clouds, doc.DefaultCloud, nil
+	}
+
+	doc := map[string]interface{}{}
+	if err := yaml.Unmarshal(b, &doc); err != nil {
+		return nil, "", err
+	}
+	raw := doc[o.cloudsKey]
+	if o.expandEnv {
+		raw = expandEnvTree(raw)
+	}
+	section, err := yaml.Marshal(raw)
+	if err != nil {
+		return nil, "", err
+	}
+	y := map[string]*cloudYAML{}
+	if err := yaml.Unmarshal(section, &y); err != nil {
+		return nil, "", errors.New(o.cloudsKey + ": " + err.Error())
+	}
+	var templates map[string]*authYAML
+	if raw, ok := doc["auth_templates"]; ok {
+		if o.expandEnv {
+			raw = expandEnvTree(raw)
+		}
+		section, err := yaml.Marshal(raw)
+		if err != nil {
+			return nil, "", err
+		}
+		if err := yaml.Unmarshal(section, &templates); err != nil {
+			return nil, "", errors.New("auth_templates: " + err.Error())
+		}
+	}
+	if err := applyAuthTemplates(y, templates); err != nil {
+		return nil, "", err
+	}
+	defaultCloud, _ := doc["default_cloud"].(string)
+	return y, defaultCloud, nil
+}
+
+// FromMap returns an initialized *Config wrapping a copy of the given clouds,
+// keyed by name. Later changes to the map or its values do not affect the
+// returned Config.
+func FromMap(clouds map[string]gophercloud.AuthOptions) Config {
+	cs := map[string]cloud{}
+	for k, v := range clouds {
+		cs[k] = cloud{auth: cloneAuthOptions(v)}
+	}
+	return &configImpl{clouds: cs}
+}
+
+// anyEnabled reports whether any of the clouds is enabled.
+func anyEnabled(clouds map[string]cloud) bool {
+	for _, v := range clouds {
+		if !v.disabled {
+			return true
+		}
+	}
+	return false
 }
 
-// getDefaultPaths returns a list of directories that OpenStack searches by
-// default for clouds.yaml files. This returns an error if the user’s home
-// directory cannot be discovered.
-func getDefaultPaths() ([]string, error) {
-	u, err := user.Current()
+// searchPaths returns the paths New tries, in order: the default paths, less
+// any that name the same file as an earlier one.
+func searchPaths(o *options) ([]string, error) {
+	paths, err := getDefaultPaths(o)
 	if err != nil {
-		s := "config: cannot find home directory: " + err.Error()
-		return nil, errors.New(s)
+		return nil, err
 	}
-	homeDir := u.HomeDir
-	if homeDir == "" {
-		return nil, errors.New("config: $HOME env var not set")
+	var unique []string
+	seen := map[string]bool{}
+	for _, p := range paths {
+		if abs, err := filepath.Abs(p); err == nil {
+			if seen[abs] {
+				continue
+			}
+			seen[abs] = true
+		}
+		unique = append(unique, p)
 	}
+	return unique, nil
+}
+
+// getDefaultPaths returns a list of files that OpenStack searches by default
+// for clouds, in the order documented by New. If the user’s home directory
+// cannot be discovered, the path under it is left out rather than failing the
+// search, so the package still works in minimal containers.
+func getDefaultPaths(o *options) ([]string, error) {
 	f := "clouds.yaml"
-	return []string{
-		filepath.Join("./", f),
-		filepath.Join(homeDir, ".config/openstack", f),
-		filepath.Join("/etc/openstack", f),
-	}, nil
+	var paths []string
+	if p := os.Getenv("OS_CLIENT_CONFIG_FILE"); p != "" {
+		paths = append(paths, p)
+	}
+	paths = append(paths, filepath.Join("./", f))
+	if d := os.Getenv("OS_CONFIG_DIR"); d != "" {
+		paths = append(paths, filepath.Join(d, f))
+	}
+	if d := o.homeDir(); d != "" {
+		paths = append(paths, filepath.Join(d, o.userConfigDir, f))
+	}
+	return append(paths, filepath.Join("/etc/openstack", f)), nil
 }
 
+// homeDir returns the user’s home directory, from the function set by
+// WithHomeDirFunc or else the default, or the empty string if there is none.
+func (o *options) homeDir() string {
+	if o.homeDirFunc != nil {
+		d, err := o.homeDirFunc()
+		if err != nil {
+			return ""
+		}
+		return d
+	}
+	return defaultHomeDir()
+}
+
+// defaultHomeDir returns the user’s home directory, from $HOME or else the
+// user’s passwd entry, or the empty string if neither gives one.
+func defaultHomeDir() string {
+	if d, err := os.UserHomeDir(); err == nil && d != "" {
+		return d
+	}
+	if u, err := user.Current(); err == nil {
+		return u.HomeDir
+	}
+	return ""
+}
+
+// errNoConfigFile is returned by New when none of the paths it searches holds
+// a usable clouds.yaml file.
+var errNoConfigFile = errors.New("config: no usable clouds.yaml file found")
+
+// ErrEmptyConfig is the Err of a *ParseError for a clouds.yaml file that
+// defines no clouds, including one that is empty or contains only comments.
+var ErrEmptyConfig = errors.New("config is empty")
+
 // ParseError represents an error parsing a clouds.yaml file.
 type ParseError struct {
 	File string
@@ -149,9 +1441,33 @@ type ParseError struct {
 }
 
 func (e *ParseError) Error() string {
-	msg := "config: cannot parse " + e.File
+	msg := "config: cannot parse"
+	if e.File != "" {
+		msg += " " + e.File
+	}
 	if e.Err != nil {
 		return msg + ": " + e.Err.Error()
 	}
 	return msg
 }
+
+// FileError represents an error opening a clouds.yaml file, such as one that
+// does not exist or that the user lacks permission to read. Err is the error
+// from the file system, so errors.Is tells these cases apart:
+//
+//	if errors.Is(err, os.ErrPermission) {
+//		// The file exists but cannot be read.
+//	}
+type FileError struct {
+	File string
+	Err  error
+}
+
+func (e *FileError) Error() string {
+	return "config: " + e.Err.Error()
+}
+
+// Unwrap returns the underlying error.
+func (e *FileError) Unwrap() error {
+	return e.Err
+}
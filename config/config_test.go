@@ -0,0 +1,316 @@
+package config
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"github.com/gophercloud/gophercloud"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const validClouds = `
+clouds:
+  a:
+    auth: {auth_url: http://a/v3, username: ua, password: pa}
+`
+
+func TestNewSkipsEmptyFiles(t *testing.T) {
+	for _, empty := range []string{"", "   \n\t\n", "# only\n# comments\n", "---\n", "--- # a comment\n...\n"} {
+		dir := isolateSearch(t)
+		writeFile(t, filepath.Join(dir, "clouds.yaml"), empty)
+		later := filepath.Join(dir, ".config", "openstack", "clouds.yaml")
+		if err := os.MkdirAll(filepath.Dir(later), 0o700); err != nil {
+			t.Fatal(err)
+		}
+		writeFile(t, later, validClouds)
+		conf, path, err := NewVerbose()
+		if err != nil {
+			t.Fatalf("NewVerbose with an empty file %q: %v", empty, err)
+		}
+		if path != later {
+			t.Errorf("NewVerbose with an empty file %q chose %s, want %s", empty, path, later)
+		}
+		if _, err := conf.Get("a"); err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+func TestNewStopsAtMalformedFile(t *testing.T) {
+	dir := isolateSearch(t)
+	writeFile(t, filepath.Join(dir, "clouds.yaml"), "clouds: [\n")
+	later := filepath.Join(dir, ".config", "openstack", "clouds.yaml")
+	if err := os.MkdirAll(filepath.Dir(later), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, later, validClouds)
+	_, err := New()
+	if parseErr, ok := err.(*ParseError); !ok || parseErr.Err == ErrEmptyConfig {
+		t.Errorf("New with a malformed file error = %v, want a *ParseError", err)
+	}
+}
+
+func TestFromFileEmpty(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "clouds.yaml")
+	writeFile(t, path, "# nothing here\n")
+	_, err := FromFile(path)
+	if parseErr, ok := err.(*ParseError); !ok || parseErr.Err != ErrEmptyConfig {
+		t.Errorf("FromFile of an empty file error = %v, want a *ParseError wrapping ErrEmptyConfig", err)
+	}
+}
+
+func gzipped(t *testing.T, s string) []byte {
+	t.Helper()
+	var b bytes.Buffer
+	zw := gzip.NewWriter(&b)
+	if _, err := zw.Write([]byte(s)); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return b.Bytes()
+}
+
+func TestFromFileGzip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "clouds.yaml.gz")
+	if err := os.WriteFile(path, gzipped(t, validClouds), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	conf, err := FromFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts, err := conf.Get("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opts.IdentityEndpoint != "http://a/v3" || opts.Username != "ua" {
+		t.Errorf("Get from a gzipped file = %+v", opts)
+	}
+	if _, err := FromReader(bytes.NewReader(gzipped(t, validClouds))); err != nil {
+		t.Errorf("FromReader of gzipped content: %v", err)
+	}
+}
+
+func TestFromFileCorruptGzip(t *testing.T) {
+	z := gzipped(t, validClouds)
+	for name, b := range map[string][]byte{
+		"truncated":    z[:len(z)/2],
+		"bad header":   append([]byte{0x1f, 0x8b, 0xff}, z[3:]...),
+		"bad checksum": append(append([]byte{}, z[:len(z)-8]...), 0, 0, 0, 0, 0, 0, 0, 0),
+	} {
+		path := filepath.Join(t.TempDir(), "clouds.yaml.gz")
+		if err := os.WriteFile(path, b, 0o600); err != nil {
+			t.Fatal(err)
+		}
+		_, err := FromFile(path)
+		parseErr, ok := err.(*ParseError)
+		if !ok || !strings.HasPrefix(parseErr.Err.Error(), "cannot decompress: ") {
+			t.Errorf("FromFile of %s gzip error = %v, want a *ParseError that it cannot decompress", name, err)
+		}
+	}
+}
+
+func TestWithCloudsKey(t *testing.T) {
+	doc := `
+clouds:
+  ignored:
+    auth: {auth_url: http://ignored/v3}
+openstack:
+  a:
+    auth: {auth_url: http://a/v3, username: ua, password: pa}
+`
+	path := filepath.Join(t.TempDir(), "clouds.yaml")
+	writeFile(t, path, doc)
+	fromFile, err := FromFile(path, WithCloudsKey("openstack"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fromBytes, err := FromBytes([]byte(doc), WithCloudsKey("openstack"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, conf := range []Config{fromFile, fromBytes} {
+		if got := conf.Names(); !reflect.DeepEqual(got, []string{"a"}) {
+			t.Errorf("Names with a custom clouds key = %v, want [a]", got)
+		}
+		if opts, err := conf.Get("a"); err != nil || opts.IdentityEndpoint != "http://a/v3" {
+			t.Errorf("Get(a) with a custom clouds key = %+v, %v", opts, err)
+		}
+	}
+
+	conf, err := FromBytes([]byte(doc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := conf.Names(); !reflect.DeepEqual(got, []string{"ignored"}) {
+		t.Errorf("Names with the default clouds key = %v, want [ignored]", got)
+	}
+	_, err = FromBytes([]byte(validClouds), WithCloudsKey("openstack"))
+	if parseErr, ok := err.(*ParseError); !ok || parseErr.Err != ErrEmptyConfig {
+		t.Errorf("FromBytes without the custom clouds key error = %v, want ErrEmptyConfig", err)
+	}
+}
+
+func TestWithTransform(t *testing.T) {
+	t.Setenv("OS_CLOUD", "")
+	t.Setenv("OS_AUTH_URL", "http://keystone.internal:5000/v3")
+	rewrite := func(name string, opts gophercloud.AuthOptions) gophercloud.AuthOptions {
+		u, err := url.Parse(opts.IdentityEndpoint)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if u.Hostname() == "keystone.internal" {
+			u.Host = "keystone.example.com:" + u.Port()
+		}
+		opts.IdentityEndpoint = u.String()
+		return opts
+	}
+	var order []string
+	trace := func(tag string) func(string, gophercloud.AuthOptions) gophercloud.AuthOptions {
+		return func(name string, opts gophercloud.AuthOptions) gophercloud.AuthOptions {
+			if name == "a" {
+				order = append(order, tag+" "+opts.IdentityEndpoint)
+			}
+			return opts
+		}
+	}
+	conf, err := FromBytes([]byte(validClouds), WithEnvOverlay(), WithTransform(trace("first")), WithTransform(rewrite), WithTransform(trace("last")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts, err := conf.Get("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "http://keystone.example.com:5000/v3"; opts.IdentityEndpoint != want {
+		t.Errorf("Get(a).IdentityEndpoint = %s, want %s", opts.IdentityEndpoint, want)
+	}
+	want := []string{"first http://keystone.internal:5000/v3", "last http://keystone.example.com:5000/v3"}
+	if !reflect.DeepEqual(order, want) {
+		t.Errorf("transforms saw %q, want %q", order, want)
+	}
+	_, sources, err := conf.EffectiveCloud("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !containsSource(sources, Source{"auth.auth_url", LayerTransform}) {
+		t.Errorf("EffectiveCloud(a) sources = %+v, want auth.auth_url from the transform", sources)
+	}
+}
+
+func containsSource(sources []Source, s Source) bool {
+	for _, e := range sources {
+		if e == s {
+			return true
+		}
+	}
+	return false
+}
+
+func TestWithStrictDiscovery(t *testing.T) {
+	dir := isolateSearch(t)
+	user := filepath.Join(dir, ".config", "openstack", "clouds.yaml")
+	if err := os.MkdirAll(filepath.Dir(user), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, user, validClouds)
+
+	// A single match, with an empty file that does not count, loads.
+	writeFile(t, filepath.Join(dir, "clouds.yaml"), "# empty\n")
+	if _, path, err := NewVerbose(WithStrictDiscovery()); err != nil || path != user {
+		t.Errorf("NewVerbose with one match = %s, %v; want %s", path, err, user)
+	}
+
+	// Two matches fail, naming both, unless discovery is first-wins.
+	writeFile(t, filepath.Join(dir, "clouds.yaml"), validClouds)
+	_, err := New(WithStrictDiscovery())
+	if want := "config: more than one clouds.yaml file found: clouds.yaml, " + user; err == nil || err.Error() != want {
+		t.Errorf("New with two matches error = %v, want %s", err, want)
+	}
+	if _, path, err := NewVerbose(); err != nil || path != "clouds.yaml" {
+		t.Errorf("NewVerbose without strict discovery = %s, %v; want the first match", path, err)
+	}
+
+	// Locations naming the same file count once.
+	os.Remove(user)
+	t.Setenv("OS_CONFIG_DIR", dir)
+	if _, path, err := NewVerbose(WithStrictDiscovery()); err != nil || path != "clouds.yaml" {
+		t.Errorf("NewVerbose with one file found twice = %s, %v; want clouds.yaml", path, err)
+	}
+}
+
+func TestWithHomeDirFunc(t *testing.T) {
+	home := isolateSearch(t)
+	stub := t.TempDir()
+	for _, d := range []string{home, stub} {
+		p := filepath.Join(d, ".config", "openstack", "clouds.yaml")
+		if err := os.MkdirAll(filepath.Dir(p), 0o700); err != nil {
+			t.Fatal(err)
+		}
+		writeFile(t, p, validClouds)
+	}
+	want := filepath.Join(stub, ".config", "openstack", "clouds.yaml")
+	resolver := func() (string, error) { return stub, nil }
+	if _, path, err := NewVerbose(WithHomeDirFunc(resolver)); err != nil || path != want {
+		t.Errorf("NewVerbose with a stub resolver = %s, %v; want %s", path, err, want)
+	}
+
+	failing := func() (string, error) { return "", errors.New("no passwd entry") }
+	if _, path, err := NewVerbose(WithHomeDirFunc(failing)); err != errNoConfigFile {
+		t.Errorf("NewVerbose with a failing resolver = %s, %v; want no file found", path, err)
+	}
+
+	want = filepath.Join(home, ".config", "openstack", "clouds.yaml")
+	if _, path, err := NewVerbose(WithHomeDirFunc(resolver), WithHomeDirFunc(nil)); err != nil || path != want {
+		t.Errorf("NewVerbose with a nil resolver = %s, %v; want the default %s", path, err, want)
+	}
+}
+
+const allDisabledClouds = `
+clouds:
+  a:
+    disabled: true
+    auth: {auth_url: http://a/v3}
+`
+
+func TestAllDisabled(t *testing.T) {
+	dir := isolateSearch(t)
+	first := filepath.Join(dir, "clouds.yaml")
+	writeFile(t, first, allDisabledClouds)
+	later := filepath.Join(dir, ".config", "openstack", "clouds.yaml")
+	if err := os.MkdirAll(filepath.Dir(later), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, later, validClouds)
+
+	// By default, the file is usable and yields no enabled clouds.
+	conf, err := FromFile(first)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := conf.Names(); len(got) != 0 {
+		t.Errorf("Names of an all-disabled file = %v, want none", got)
+	}
+	if got := len(conf.AllIncludingDisabled()); got != 1 {
+		t.Errorf("AllIncludingDisabled of an all-disabled file has %d clouds, want 1", got)
+	}
+	if _, path, err := NewVerbose(); err != nil || path != "clouds.yaml" {
+		t.Errorf("NewVerbose = %s, %v; want the all-disabled file", path, err)
+	}
+
+	// With WithAllDisabledAsEmpty, it is empty and the search goes on.
+	_, err = FromFile(first, WithAllDisabledAsEmpty())
+	if parseErr, ok := err.(*ParseError); !ok || parseErr.Err != ErrEmptyConfig {
+		t.Errorf("FromFile with WithAllDisabledAsEmpty error = %v, want ErrEmptyConfig", err)
+	}
+	if _, path, err := NewVerbose(WithAllDisabledAsEmpty()); err != nil || path != later {
+		t.Errorf("NewVerbose with WithAllDisabledAsEmpty = %s, %v; want %s", path, err, later)
+	}
+}
@@ -0,0 +1,114 @@
+package config
+
+import (
+	"errors"
+	"gopkg.in/yaml.v2"
+	"os"
+	"strings"
+)
+
+// CredentialProvider supplies the secrets of clouds from a source outside
+// clouds.yaml, such as a secret manager. Providers are composed into a chain
+// with WithCredentialProviders.
+type CredentialProvider interface {
+	// Credential returns the named secret field of a cloud, one of
+	// password, token, passcode, or application_credential_secret. The
+	// boolean reports whether the provider has the secret; if it does not,
+	// the next provider in the chain is asked. An error stops the chain
+	// and fails the call.
+	Credential(cloud, field string) (string, bool, error)
+}
+
+// CredentialProviderFunc adapts a function to a CredentialProvider.
+type CredentialProviderFunc func(cloud, field string) (string, bool, error)
+
+// Credential satisfies the CredentialProvider interface.
+func (f CredentialProviderFunc) Credential(cloud, field string) (string, bool, error) {
+	return f(cloud, field)
+}
+
+// WithCredentialProviders adds providers to the chain that supplies secrets
+// no other source did. When a method that hands out or checks credentials,
+// such as Get, GetUnscoped, Authenticate, Validate, or VerifyAll, needs a
+// secret that the cloud’s auth type requires, such as the password, and the
+// cloud has none, the providers are asked in the order given, and
+// the first that has the secret supplies it. Only if none does is the
+// function set by WithSecretPrompt called.
+//
+// As with prompted secrets, supplied secrets are used for that call only and
+// never stored, so they never appear in errors, exports, or redacted output.
+func WithCredentialProviders(providers ...CredentialProvider) Option {
+	return func(o *options) error {
+		for _, p := range providers {
+			if p == nil {
+				return errors.New("config: credential provider must not be nil")
+			}
+		}
+		o.providers = append(o.providers, providers...)
+		return nil
+	}
+}
+
+// EnvCredentials returns a CredentialProvider that reads secrets from
+// environment variables named with the given prefix, such as OS_ for
+// OS_PASSWORD. A variable for one cloud, such as OS_PROD_PASSWORD for the
+// cloud prod, is preferred over the variable for every cloud; hyphens in the
+// cloud’s name become underscores, as in OS_MY_CLOUD_PASSWORD. Variables that
+// are unset or empty are ignored.
+func EnvCredentials(prefix string) CredentialProvider {
+	return CredentialProviderFunc(func(cloud, field string) (string, bool, error) {
+		perCloud := strings.Replace(cloud, "-", "_", -1) + "_" + field
+		for _, name := range []string{envName(prefix, perCloud), envName(prefix, field)} {
+			if value := os.Getenv(name); value != "" {
+				return value, true, nil
+			}
+		}
+		return "", false, nil
+	})
+}
+
+// FileCredentials returns a CredentialProvider that supplies secrets from a
+// YAML file mapping cloud names to their secret fields, as in
+//
+//	prod:
+//	  password: s3cret
+//	staging:
+//	  application_credential_secret: an0ther
+//
+// The file is read once, by FileCredentials. This returns a *FileError if the
+// file cannot be read, or a *ParseError if it is in an invalid format.
+func FileCredentials(path string) (CredentialProvider, error) {
+	o, err := newOptions(nil)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, &FileError{path, err}
+	}
+	defer f.Close()
+	b, err := readAll(f, path, o)
+	if err != nil {
+		return nil, err
+	}
+	var secrets map[string]map[string]string
+	if err := yaml.Unmarshal(b, &secrets); err != nil {
+		return nil, &ParseError{path, err}
+	}
+	return CredentialProviderFunc(func(cloud, field string) (string, bool, error) {
+		value := secrets[cloud][field]
+		return value, value != "", nil
+	}), nil
+}
+
+// provideCredential asks each provider in turn for a cloud’s secret field,
+// returning the first secret supplied.
+func provideCredential(providers []CredentialProvider, cloud, field string) (string, bool, error) {
+	for _, p := range providers {
+		value, ok, err := p.Credential(cloud, field)
+		if err != nil || ok {
+			return value, ok, err
+		}
+	}
+	return "", false, nil
+}
@@ -0,0 +1,121 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCredentialProvidersChain(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "secrets.yaml")
+	if err := ioutil.WriteFile(path, []byte("b:\n  password: from-file\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	file, err := FileCredentials(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("TEST_MY_A_PASSWORD", "from-env")
+	prompted := 0
+	c, err := FromBytes([]byte(`
+clouds:
+  my-a:
+    auth: {auth_url: http://127.0.0.1:1/v3, username: u}
+  b:
+    auth: {auth_url: http://127.0.0.1:1/v3, username: u}
+  c:
+    auth: {auth_url: http://127.0.0.1:1/v3, username: u}
+  d:
+    auth: {auth_url: http://127.0.0.1:1/v3, username: u, password: from-clouds}
+`),
+		WithCredentialProviders(EnvCredentials("TEST_"), file),
+		WithSecretPrompt(func(cloud, field string) (string, error) {
+			prompted++
+			return "prompted", nil
+		}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]string{
+		"my-a": "from-env",
+		"b":    "from-file",
+		"c":    "prompted",
+		"d":    "from-clouds",
+	} {
+		for method, get := range map[string]func(string) (string, error){
+			"Get": func(name string) (string, error) {
+				opts, err := c.Get(name)
+				return opts.Password, err
+			},
+			"GetUnscoped": func(name string) (string, error) {
+				opts, err := c.GetUnscoped(name)
+				return opts.Password, err
+			},
+		} {
+			got, err := get(name)
+			if err != nil {
+				t.Errorf("%s(%q): %v", method, name, err)
+			} else if got != want {
+				t.Errorf("%s(%q) password = %q, want %q", method, name, got, want)
+			}
+		}
+		if err := c.Validate(name); err != nil {
+			t.Errorf("Validate(%q): %v", name, err)
+		}
+	}
+	if prompted != 3 {
+		t.Errorf("prompt called %d times, want 3", prompted)
+	}
+	if opts := c.AllIncludingDisabled()["b"]; opts.Password != "" {
+		t.Errorf("provided password stored in Config: %q", opts.Password)
+	}
+}
+
+func TestCredentialProviderErrorStopsChain(t *testing.T) {
+	next := false
+	c, err := FromBytes([]byte("clouds:\n  a:\n    auth: {auth_url: http://127.0.0.1:1/v3, username: u}\n"),
+		WithCredentialProviders(
+			CredentialProviderFunc(func(cloud, field string) (string, bool, error) {
+				return "", false, errors.New("vault sealed")
+			}),
+			CredentialProviderFunc(func(cloud, field string) (string, bool, error) {
+				next = true
+				return "x", true, nil
+			}),
+		))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "config: cloud `a`: cannot obtain password: vault sealed"
+	if err := c.VerifyAll(context.Background())["a"]; err == nil || err.Error() != want {
+		t.Errorf("VerifyAll()[a] = %v, want %s", err, want)
+	}
+	if _, err := c.GetV3Scoped("a", "p", "d"); err == nil || err.Error() != want {
+		t.Errorf("GetV3Scoped() = %v, want %s", err, want)
+	}
+	if next {
+		t.Error("chain continued past a failing provider")
+	}
+}
+
+func TestFileCredentialsErrors(t *testing.T) {
+	if _, err := FileCredentials(filepath.Join(t.TempDir(), "missing.yaml")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("FileCredentials(missing) = %v, want a not-exist *FileError", err)
+	}
+	path := filepath.Join(t.TempDir(), "bad.yaml")
+	if err := ioutil.WriteFile(path, []byte("a: [\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := FileCredentials(path); err == nil {
+		t.Error("FileCredentials(bad) = nil error")
+	} else if _, ok := err.(*ParseError); !ok {
+		t.Errorf("FileCredentials(bad) = %T, want *ParseError", err)
+	}
+	if _, err := FromBytes([]byte("clouds: {a: {auth: {auth_url: x}}}"), WithCredentialProviders(nil)); err == nil {
+		t.Error("WithCredentialProviders(nil) = nil error")
+	}
+}
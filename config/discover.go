@@ -0,0 +1,81 @@
+package config
+
+import (
+	"os"
+)
+
+// PathResult describes what Discover found at one candidate clouds.yaml path.
+type PathResult struct {
+	// Path is the candidate file path.
+	Path string
+
+	// Exists reports whether a file is present at Path.
+	Exists bool
+
+	// Parsed reports whether the file was read and parsed successfully.
+	Parsed bool
+
+	// Clouds is the number of clouds defined in the file, if it parsed.
+	Clouds int
+
+	// Err is the error encountered reading or parsing the file, if any.
+	Err error
+}
+
+// SearchPaths returns the paths of the clouds.yaml files New tries, in the
+// order it tries them, as documented by New: environment variables such as
+// OS_CLIENT_CONFIG_FILE and OS_CONFIG_DIR and options such as
+// WithUserConfigDir are taken into account, and a path naming the same file
+// as an earlier one is left out. No file is read, so this suits printing
+// where a program looks for its config.
+//
+// SearchPaths accepts the same options as New and returns an error only if
+// they are invalid or the search paths cannot be determined.
+func SearchPaths(opts ...Option) ([]string, error) {
+	o, err := newOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+	return searchPaths(o)
+}
+
+// Discover reports, for each path New would search, whether a clouds.yaml
+// file exists there, whether it parses, and how many clouds it defines.
+//
+// This is strictly informational: every candidate is examined, and the
+// results are returned in search order without selecting one. It is meant for
+// diagnosing why a particular file is or isn’t picked up by New.
+//
+// Discover accepts the same options as New and returns an error only if they
+// are invalid or the search paths cannot be determined.
+func Discover(opts ...Option) ([]PathResult, error) {
+	o, err := newOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+	paths, err := searchPaths(o)
+	if err != nil {
+		return nil, err
+	}
+	results := make([]PathResult, 0, len(paths))
+	for _, p := range paths {
+		r := PathResult{Path: p}
+		if _, err := os.Stat(p); err != nil {
+			if !os.IsNotExist(err) {
+				r.Err = err
+			}
+			results = append(results, r)
+			continue
+		}
+		r.Exists = true
+		conf, err := fromFile(p, o)
+		if err != nil {
+			r.Err = err
+		} else {
+			r.Parsed = true
+			r.Clouds = len(conf.GetAll())
+		}
+		results = append(results, r)
+	}
+	return results, nil
+}
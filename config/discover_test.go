@@ -0,0 +1,46 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestDiscoverMatchesSearchPaths(t *testing.T) {
+	dir := isolateSearch(t)
+	// OS_CONFIG_DIR names the working directory, so its clouds.yaml is
+	// the one already searched there and is reported once.
+	t.Setenv("OS_CONFIG_DIR", dir)
+	writeFile(t, filepath.Join(dir, "clouds.yaml"), validClouds)
+	user := filepath.Join(dir, ".config", "openstack", "clouds.yaml")
+	if err := os.MkdirAll(filepath.Dir(user), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, user, "clouds: [\n")
+
+	paths, err := SearchPaths()
+	if err != nil {
+		t.Fatal(err)
+	}
+	results, err := Discover()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, r := range results {
+		got = append(got, r.Path)
+	}
+	if !reflect.DeepEqual(got, paths) {
+		t.Fatalf("Discover paths = %v, want SearchPaths %v", got, paths)
+	}
+	if want := []string{"clouds.yaml", user, "/etc/openstack/clouds.yaml"}; !reflect.DeepEqual(paths, want) {
+		t.Errorf("SearchPaths = %v, want %v", paths, want)
+	}
+	if r := results[0]; !r.Exists || !r.Parsed || r.Clouds != 1 || r.Err != nil {
+		t.Errorf("Discover result for the valid file = %+v", r)
+	}
+	if r := results[1]; !r.Exists || r.Parsed || r.Err == nil {
+		t.Errorf("Discover result for the malformed file = %+v", r)
+	}
+}
@@ -0,0 +1,58 @@
+package config
+
+import (
+	"github.com/gophercloud/gophercloud"
+	tokens3 "github.com/gophercloud/gophercloud/openstack/identity/v3/tokens"
+	"reflect"
+)
+
+// cachedDomains holds the IDs of the domains a cloud’s config names, keyed by
+// domain name, as learned from a token, along with the config they apply to.
+type cachedDomains struct {
+	cloud cloud
+	ids   map[string]string
+}
+
+// withDomainIDs returns a copy of opts in which each domain given by a name
+// whose ID the cache has learned for the cloud is given by that ID instead,
+// sparing Keystone from resolving the name again.
+func (cc *clientCache) withDomainIDs(name string, v cloud) gophercloud.AuthOptions {
+	opts := cloneAuthOptions(v.auth)
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	d, ok := cc.domains[name]
+	if !ok || !reflect.DeepEqual(d.cloud, v) {
+		return opts
+	}
+	if id := d.ids[opts.DomainName]; id != "" && opts.DomainID == "" {
+		opts.DomainID, opts.DomainName = id, ""
+	}
+	if s := opts.Scope; s != nil {
+		if id := d.ids[s.DomainName]; id != "" && s.DomainID == "" {
+			s.DomainID, s.DomainName = id, ""
+		}
+	}
+	return opts
+}
+
+// putDomains records the IDs of the user and project domains of an
+// authenticated v3 client’s token, for later authentications to the cloud.
+func (cc *clientCache) putDomains(name string, v cloud, p *gophercloud.ProviderClient) {
+	r, ok := p.GetAuthResult().(tokens3.CreateResult)
+	if !ok {
+		return
+	}
+	ids := map[string]string{}
+	if u, err := r.ExtractUser(); err == nil && u != nil && u.Domain.Name != "" {
+		ids[u.Domain.Name] = u.Domain.ID
+	}
+	if pr, err := r.ExtractProject(); err == nil && pr != nil && pr.Domain.Name != "" {
+		ids[pr.Domain.Name] = pr.Domain.ID
+	}
+	if len(ids) == 0 {
+		return
+	}
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	cc.domains[name] = cachedDomains{cloud: v, ids: ids}
+}
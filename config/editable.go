@@ -0,0 +1,189 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	yaml3 "gopkg.in/yaml.v3"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Editable is a clouds.yaml file loaded as a document tree for editing on a
+// user’s behalf. Unlike a Config, it keeps everything in the file, including
+// comments, key order, and fields this package does not understand, so that
+// Save writes back only the changes made. Indentation is normalized to two
+// spaces.
+//
+// An Editable is not safe for concurrent use.
+type Editable struct {
+	path string
+	doc  yaml3.Node
+}
+
+// OpenEditable loads a clouds.yaml file for editing. The file need not define
+// any clouds, and a clouds key with no value counts as an empty mapping, but
+// the file must be a YAML mapping if it holds more than comments. This
+// returns a *ParseError if the file cannot be parsed.
+func OpenEditable(path string) (*Editable, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, &FileError{path, err}
+	}
+	e := &Editable{path: path}
+	if err := yaml3.Unmarshal(b, &e.doc); err != nil {
+		return nil, &ParseError{path, err}
+	}
+	if e.doc.Kind == 0 {
+		// A file holding only comments decodes to nothing, so they are
+		// kept by hand, ahead of whatever is set later.
+		e.doc = yaml3.Node{
+			Kind:        yaml3.DocumentNode,
+			HeadComment: comments(b),
+			Content:     []*yaml3.Node{{Kind: yaml3.MappingNode}},
+		}
+	}
+	toMapping(e.doc.Content[0])
+	if e.doc.Content[0].Kind != yaml3.MappingNode {
+		return nil, &ParseError{path, errors.New("document is not a mapping")}
+	}
+	return e, nil
+}
+
+// Set sets a field of a cloud to value, which may be any value that YAML can
+// encode, creating the cloud and any enclosing mappings as needed. The field
+// is a clouds.yaml field name, with the names of nested fields separated by
+// dots, as in region_name or auth.password. A comment on a field that already
+// exists is kept.
+func (e *Editable) Set(cloud, field string, value interface{}) error {
+	if field == "" {
+		return errors.New("config: field name is empty")
+	}
+	var n yaml3.Node
+	if err := n.Encode(value); err != nil {
+		return errors.New("config: " + err.Error())
+	}
+	m := e.doc.Content[0]
+	keys := append([]string{"clouds", cloud}, strings.Split(field, ".")...)
+	for _, k := range keys[:len(keys)-1] {
+		next := lookup(m, k)
+		if next == nil {
+			next = &yaml3.Node{Kind: yaml3.MappingNode}
+			m.Content = append(m.Content, &yaml3.Node{Kind: yaml3.ScalarNode, Value: k}, next)
+		}
+		toMapping(next)
+		if next.Kind != yaml3.MappingNode {
+			return errors.New("config: cloud `" + cloud + "`: `" + k + "` is not a mapping")
+		}
+		m = next
+	}
+	k := keys[len(keys)-1]
+	if old := lookup(m, k); old != nil {
+		n.HeadComment, n.LineComment, n.FootComment = old.HeadComment, old.LineComment, old.FootComment
+		*old = n
+		return nil
+	}
+	m.Content = append(m.Content, &yaml3.Node{Kind: yaml3.ScalarNode, Value: k}, &n)
+	return nil
+}
+
+// Remove removes a field of a cloud, named as for Set, or the whole cloud if
+// field is empty. Removing something that does not exist does nothing.
+func (e *Editable) Remove(cloud, field string) error {
+	keys := []string{"clouds", cloud}
+	if field != "" {
+		keys = append(keys, strings.Split(field, ".")...)
+	}
+	m := e.doc.Content[0]
+	for _, k := range keys[:len(keys)-1] {
+		if m = lookup(m, k); m == nil || m.Kind != yaml3.MappingNode {
+			return nil
+		}
+	}
+	k := keys[len(keys)-1]
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == k {
+			m.Content = append(m.Content[:i], m.Content[i+2:]...)
+			return nil
+		}
+	}
+	return nil
+}
+
+// Bytes returns the edited document.
+func (e *Editable) Bytes() ([]byte, error) {
+	var buf bytes.Buffer
+	enc := yaml3.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(&e.doc); err != nil {
+		return nil, errors.New("config: " + err.Error())
+	}
+	if err := enc.Close(); err != nil {
+		return nil, errors.New("config: " + err.Error())
+	}
+	return buf.Bytes(), nil
+}
+
+// Save writes the edited document back to the file it was loaded from,
+// keeping the file’s permissions. The file is replaced atomically, so a
+// failed Save leaves it unchanged.
+func (e *Editable) Save() error {
+	b, err := e.Bytes()
+	if err != nil {
+		return err
+	}
+	fi, err := os.Stat(e.path)
+	if err != nil {
+		return errors.New("config: " + err.Error())
+	}
+	f, err := ioutil.TempFile(filepath.Dir(e.path), "."+filepath.Base(e.path)+".*")
+	if err != nil {
+		return errors.New("config: " + err.Error())
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(b); err != nil {
+		f.Close()
+		return errors.New("config: " + err.Error())
+	}
+	if err := f.Chmod(fi.Mode().Perm()); err != nil {
+		f.Close()
+		return errors.New("config: " + err.Error())
+	}
+	if err := f.Close(); err != nil {
+		return errors.New("config: " + err.Error())
+	}
+	if err := os.Rename(f.Name(), e.path); err != nil {
+		return errors.New("config: " + err.Error())
+	}
+	return nil
+}
+
+// lookup returns the value of a key in a mapping node, or nil if the key is
+// not present.
+func lookup(m *yaml3.Node, key string) *yaml3.Node {
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			return m.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// toMapping makes a null node, such as the value of a clouds key with nothing
+// after it, an empty mapping, keeping its comments.
+func toMapping(n *yaml3.Node) {
+	if n.Kind == yaml3.ScalarNode && n.ShortTag() == "!!null" {
+		n.Kind, n.Tag, n.Value, n.Style = yaml3.MappingNode, "", "", 0
+	}
+}
+
+// comments returns the comment lines of a document holding nothing else, as
+// a comment for a node.
+func comments(b []byte) string {
+	lines := strings.Split(strings.TrimSpace(string(b)), "\n")
+	for i, l := range lines {
+		lines[i] = strings.TrimSpace(l)
+	}
+	return strings.Join(lines, "\n")
+}
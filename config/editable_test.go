@@ -0,0 +1,114 @@
+package config
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func editBytes(t *testing.T, content string, edit func(*Editable) error) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "clouds.yaml")
+	writeFile(t, path, content)
+	e, err := OpenEditable(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := edit(e); err != nil {
+		t.Fatal(err)
+	}
+	b, err := e.Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func TestEditableKeepsComments(t *testing.T) {
+	got := editBytes(t, `# Managed by hand.
+clouds:
+  # Production.
+  prod:
+    region_name: RegionOne # the only region
+    auth:
+      auth_url: http://prod/v3
+      password: old
+  dev:
+    auth: {auth_url: http://dev/v3}
+`, func(e *Editable) error {
+		if err := e.Set("prod", "region_name", "RegionTwo"); err != nil {
+			return err
+		}
+		if err := e.Set("prod", "auth.password", "new"); err != nil {
+			return err
+		}
+		return e.Remove("dev", "")
+	})
+	want := `# Managed by hand.
+clouds:
+  # Production.
+  prod:
+    region_name: RegionTwo # the only region
+    auth:
+      auth_url: http://prod/v3
+      password: new
+`
+	if got != want {
+		t.Errorf("edited document =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestEditableCommentsOnly(t *testing.T) {
+	got := editBytes(t, "# Clouds for the lab.\n\n#   Ask ops before adding one.\n", func(e *Editable) error {
+		return e.Set("lab", "auth.auth_url", "http://lab/v3")
+	})
+	want := `# Clouds for the lab.
+
+#   Ask ops before adding one.
+
+clouds:
+  lab:
+    auth:
+      auth_url: http://lab/v3
+`
+	if got != want {
+		t.Errorf("edited comments-only document =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestEditableNullClouds(t *testing.T) {
+	for _, content := range []string{"# Clouds.\nclouds:\n", "# Clouds.\nclouds: ~\n", "clouds:\n  lab:\n"} {
+		got := editBytes(t, content, func(e *Editable) error {
+			return e.Set("lab", "region_name", "RegionOne")
+		})
+		conf, err := FromBytes([]byte(got + "    auth: {auth_url: http://lab/v3}\n"))
+		if err != nil {
+			t.Errorf("edited document from %q =\n%s\ndoes not load: %v", content, got, err)
+			continue
+		}
+		if eo, err := conf.EndpointOpts("lab", ""); err != nil || eo.Region != "RegionOne" {
+			t.Errorf("edited document from %q =\n%s\nhas region %+v, %v", content, got, eo, err)
+		}
+	}
+	got := editBytes(t, "# Clouds.\nclouds: # none yet\n", func(e *Editable) error {
+		return e.Set("lab", "region_name", "RegionOne")
+	})
+	if want := "# Clouds.\nclouds: # none yet\n  lab:\n    region_name: RegionOne\n"; got != want {
+		t.Errorf("edited document =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestEditableNotMapping(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "clouds.yaml")
+	writeFile(t, path, "- a\n- b\n")
+	if _, err := OpenEditable(path); err == nil {
+		t.Error("OpenEditable of a list succeeded")
+	}
+	writeFile(t, path, "clouds: [a]\n")
+	e, err := OpenEditable(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := e.Set("a", "region_name", "r"); err == nil || err.Error() != "config: cloud `a`: `clouds` is not a mapping" {
+		t.Errorf("Set with a list of clouds error = %v", err)
+	}
+}
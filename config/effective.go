@@ -0,0 +1,115 @@
+package config
+
+import (
+	"github.com/gophercloud/gophercloud"
+	"os"
+	"path/filepath"
+)
+
+// envCloudName is the name LoadEffective gives the cloud it builds from
+// environment variables alone, as OpenStack’s own tools do.
+const envCloudName = "envvars"
+
+// LoadEffective returns the auth options for a cloud with every source of its
+// settings combined, for callers that just want working credentials. An empty
+// name selects the default cloud. It proceeds as follows:
+//
+//  1. The options are applied, with WithEnvOverlay always among them.
+//  2. The paths documented by New are searched for the first usable
+//     clouds.yaml file, with the same rules for skipping files and stopping
+//     the search.
+//  3. If a secure.yaml file sits in the same directory, its entries are
+//     merged over those of clouds.yaml, as with FromReaders.
+//  4. Each cloud is resolved in the order of precedence documented for the
+//     package: its profile, its clouds.yaml and secure.yaml entries, a secret
+//     directory, and indirections such as password_env.
+//  5. The cloud is chosen: the named one, or else the default as documented
+//     by DefaultName, which honors OS_CLOUD.
+//  6. Environment variables, such as OS_PASSWORD, override the chosen cloud’s
+//     settings, even when it was named rather than selected by OS_CLOUD.
+//  7. Secrets still missing are obtained as Get obtains them, from any
+//     providers set by WithCredentialProviders or else the function set by
+//     WithSecretPrompt.
+//  8. The cloud is checked as Validate checks it, and its options are
+//     returned as Get returns them, after any WithTransform functions.
+//
+// If no clouds.yaml file is found, the cloud is built from environment
+// variables alone, under the name envvars, as long as they set at least
+// OS_AUTH_URL; otherwise, LoadEffective returns New’s error.
+func LoadEffective(name string, opts ...Option) (gophercloud.AuthOptions, error) {
+	o, err := newOptions(append(opts[:len(opts):len(opts)], WithEnvOverlay()))
+	if err != nil {
+		return gophercloud.AuthOptions{}, err
+	}
+	o.secureFile = true
+	o.overlayCloud = name
+	conf, _, err := newVerbose(o)
+	if err == errNoConfigFile {
+		if conf, err = fromEnvOnly(o); err == nil {
+			name = envCloudName
+		}
+	}
+	if err != nil {
+		return gophercloud.AuthOptions{}, err
+	}
+	if name == "" {
+		if name, err = conf.DefaultName(); err != nil {
+			return gophercloud.AuthOptions{}, err
+		}
+	}
+	// Secrets are obtained once, from any credential provider or prompt,
+	// and the same options are both validated and returned.
+	v, err := conf.(*configImpl).cloudWithSecrets(name)
+	if err != nil {
+		return gophercloud.AuthOptions{}, err
+	}
+	if err := validateDefault(name, v); err != nil {
+		return gophercloud.AuthOptions{}, err
+	}
+	return cloneAuthOptions(v.auth), nil
+}
+
+// fromEnvOnly returns a Config holding a single cloud, named envCloudName,
+// whose settings all come from environment variables. It returns
+// errNoConfigFile if they do not set an auth URL.
+func fromEnvOnly(o *options) (Config, error) {
+	eo := *o
+	eo.format = FormatYAML
+	eo.overlayCloud = envCloudName
+	b := []byte(eo.cloudsKey + ":\n  " + envCloudName + ":\n    auth: {}\n")
+	conf, err := parse(b, "", &eo)
+	if err != nil {
+		return nil, err
+	}
+	if opts, err := conf.Get(envCloudName); err != nil || opts.IdentityEndpoint == "" {
+		return nil, errNoConfigFile
+	}
+	return conf, nil
+}
+
+// fromFileWithSecure reads and parses a clouds.yaml file from f, merging the
+// secure.yaml file in the same directory over it if there is one.
+func fromFileWithSecure(f *os.File, path string, o *options) (Config, error) {
+	b, err := readAll(f, path, o)
+	if err != nil {
+		return nil, err
+	}
+	dir := filepath.Dir(path)
+	securePath := filepath.Join(dir, "secure.yaml")
+	sf, err := os.Open(securePath)
+	if os.IsNotExist(err) {
+		return parse(b, path, o)
+	}
+	if err != nil {
+		return nil, &FileError{securePath, err}
+	}
+	defer sf.Close()
+	sb, err := readAll(sf, securePath, o)
+	if err != nil {
+		return nil, err
+	}
+	if sb == nil {
+		sb = []byte{}
+	}
+	return parseWithSecure(b, path, sb, securePath, dir, o)
+}
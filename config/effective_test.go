@@ -0,0 +1,104 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// isolateSearch points every location New searches into an empty temporary
+// directory, returning it.
+func isolateSearch(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	t.Setenv("HOME", dir)
+	t.Setenv("OS_CLIENT_CONFIG_FILE", "")
+	t.Setenv("OS_CONFIG_DIR", "")
+	t.Setenv("OS_CLOUD", "")
+	t.Setenv("OS_CLOUD_NAME", "")
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+	return dir
+}
+
+func TestLoadEffective(t *testing.T) {
+	dir := isolateSearch(t)
+	clouds := filepath.Join(dir, "clouds.yaml")
+	writeFile(t, clouds, `
+clouds:
+  a:
+    auth: {auth_url: http://a/v3, username: ua, project_name: pa}
+  b:
+    auth: {auth_url: http://b/v3, username: ub}
+`)
+	writeFile(t, filepath.Join(dir, "secure.yaml"), "clouds:\n  a:\n    auth: {password: secure}\n")
+	t.Setenv("OS_CLIENT_CONFIG_FILE", clouds)
+	t.Setenv("OS_CLOUD", "a")
+
+	opts, err := LoadEffective("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opts.IdentityEndpoint != "http://a/v3" || opts.Password != "secure" {
+		t.Errorf("LoadEffective(\"\") = %+v, want cloud a with the secure.yaml password", opts)
+	}
+
+	// The environment applies to a cloud chosen by name, too.
+	t.Setenv("OS_PASSWORD", "env")
+	if opts, err = LoadEffective("b"); err != nil {
+		t.Fatal(err)
+	}
+	if opts.Username != "ub" || opts.Password != "env" {
+		t.Errorf("LoadEffective(b) = %+v, want cloud b with the env password", opts)
+	}
+}
+
+func TestLoadEffectivePrompts(t *testing.T) {
+	dir := isolateSearch(t)
+	writeFile(t, filepath.Join(dir, "clouds.yaml"), "clouds:\n  a:\n    auth: {auth_url: http://a/v3, username: u}\n")
+	calls := 0
+	opts, err := LoadEffective("a", WithSecretPrompt(func(cloud, field string) (string, error) {
+		calls++
+		return "prompted", nil
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opts.Password != "prompted" || calls != 1 {
+		t.Errorf("password = %q after %d prompts, want prompted after 1", opts.Password, calls)
+	}
+	if _, err := LoadEffective("a"); err == nil {
+		t.Error("LoadEffective without a password = nil error")
+	}
+}
+
+func TestLoadEffectiveEnvOnly(t *testing.T) {
+	isolateSearch(t)
+	if _, err := LoadEffective(""); err != errNoConfigFile {
+		t.Errorf("LoadEffective with nothing set = %v, want %v", err, errNoConfigFile)
+	}
+	t.Setenv("OS_AUTH_URL", "http://env/v3")
+	t.Setenv("OS_USERNAME", "u")
+	t.Setenv("OS_PASSWORD", "p")
+	opts, err := LoadEffective("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opts.IdentityEndpoint != "http://env/v3" || opts.Username != "u" || opts.Password != "p" {
+		t.Errorf("LoadEffective(\"\") = %+v, want options from the environment", opts)
+	}
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := ioutil.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,44 @@
+package config
+
+import (
+	"errors"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// errNotUTF8 is the Err of a *ParseError for content that is neither UTF-8 nor
+// UTF-16 with a byte order mark.
+var errNotUTF8 = errors.New("content is not valid UTF-8; re-save the file as UTF-8")
+
+// toUTF8 transcodes content that begins with a UTF-16 byte order mark to
+// UTF-8. Any other content, the usual case, is returned unchanged without
+// being copied or scanned.
+func toUTF8(b []byte) ([]byte, error) {
+	if len(b) < 2 {
+		return b, nil
+	}
+	var hi, lo int
+	switch {
+	case b[0] == 0xff && b[1] == 0xfe:
+		hi, lo = 1, 0
+	case b[0] == 0xfe && b[1] == 0xff:
+		hi, lo = 0, 1
+	default:
+		return b, nil
+	}
+	b = b[2:]
+	if len(b)%2 != 0 {
+		return nil, errors.New("content has a UTF-16 byte order mark but an odd length")
+	}
+	units := make([]uint16, len(b)/2)
+	for i := range units {
+		units[i] = uint16(b[2*i+hi])<<8 | uint16(b[2*i+lo])
+	}
+	out := make([]byte, 0, len(b))
+	buf := make([]byte, utf8.UTFMax)
+	for _, r := range utf16.Decode(units) {
+		n := utf8.EncodeRune(buf, r)
+		out = append(out, buf[:n]...)
+	}
+	return out, nil
+}
@@ -0,0 +1,68 @@
+package config
+
+import (
+	"net"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+)
+
+// normalizeAuthURL reduces an auth_url to a form in which URLs naming the same
+// Keystone compare equal: without its scheme, default port, query, or
+// trailing slash, and with its host in lower case. The scheme is dropped so
+// that http and https forms of a URL match. A URL that cannot be parsed is
+// returned as is.
+func normalizeAuthURL(s string) string {
+	u, err := url.Parse(strings.TrimSpace(s))
+	if err != nil || u.Host == "" {
+		return s
+	}
+	host := strings.ToLower(u.Hostname())
+	if port := u.Port(); port != "" && !(port == "80" && u.Scheme == "http" || port == "443" && u.Scheme == "https") {
+		host = net.JoinHostPort(host, port)
+	}
+	p := ""
+	if u.Path != "" && u.Path != "/" {
+		p = strings.TrimSuffix(path.Clean(u.Path), "/")
+	}
+	return host + p
+}
+
+// SameEndpoint satisfies the Config interface.
+func (c *configImpl) SameEndpoint(a, b string) (bool, error) {
+	va, err := c.cloud(a)
+	if err != nil {
+		return false, err
+	}
+	vb, err := c.cloud(b)
+	if err != nil {
+		return false, err
+	}
+	if va.auth.IdentityEndpoint == "" || vb.auth.IdentityEndpoint == "" {
+		return false, nil
+	}
+	return normalizeAuthURL(va.auth.IdentityEndpoint) == normalizeAuthURL(vb.auth.IdentityEndpoint) &&
+		va.region == vb.region, nil
+}
+
+// GroupByEndpoint satisfies the Config interface.
+func (c *configImpl) GroupByEndpoint() map[string][]string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	groups := map[string][]string{}
+	for k, v := range c.clouds {
+		if v.disabled || v.auth.IdentityEndpoint == "" {
+			continue
+		}
+		e := normalizeAuthURL(v.auth.IdentityEndpoint)
+		groups[e] = append(groups[e], k)
+	}
+	for _, names := range groups {
+		sort.Strings(names)
+	}
+	if len(groups) == 0 {
+		return nil
+	}
+	return groups
+}
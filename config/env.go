@@ -0,0 +1,236 @@
+package config
+
+import (
+	"bytes"
+	"os"
+	"sort"
+	"strings"
+)
+
+// expandEnv replaces each ${NAME} in s with the value of the environment
+// variable NAME, or the empty string if it is unset. Expansion is a single
+// pass: substituted values are not themselves expanded, so a value containing
+// ${...} or $ is inserted as-is and a self-referential variable cannot loop.
+// A bare $NAME, or a ${ with no closing brace, is left untouched.
+func expandEnv(s string) string {
+	if !strings.Contains(s, "${") {
+		return s
+	}
+	var out []byte
+	for {
+		i := strings.Index(s, "${")
+		if i < 0 {
+			break
+		}
+		j := strings.IndexByte(s[i+2:], '}')
+		if j < 0 {
+			break
+		}
+		out = append(out, s[:i]...)
+		out = append(out, os.Getenv(s[i+2:i+2+j])...)
+		s = s[i+3+j:]
+	}
+	return string(append(out, s...))
+}
+
+// expandEnvTree applies expandEnv to every string value in a decoded YAML
+// tree, leaving keys alone.
+func expandEnvTree(v interface{}) interface{} {
+	switch t := v.(type) {
+	case string:
+		return expandEnv(t)
+	case map[interface{}]interface{}:
+		m := make(map[interface{}]interface{}, len(t))
+		for k, e := range t {
+			m[k] = expandEnvTree(e)
+		}
+		return m
+	case []interface{}:
+		l := make([]interface{}, len(t))
+		for i, e := range t {
+			l[i] = expandEnvTree(e)
+		}
+		return l
+	}
+	return v
+}
+
+// envCloudFields lists the string cloud entry fields, besides those of the
+// auth block, that WithEnvOverlay reads from environment variables. The
+// interface field is read as well, as a single interface.
+var envCloudFields = []string{
+	"auth_type",
+	"region_name",
+	"cacert",
+	"cert",
+	"key",
+	"compute_api_version",
+	"identity_api_version",
+	"image_api_version",
+	"network_api_version",
+	"volume_api_version",
+}
+
+// envAliases maps legacy auth field names to the names that replace them.
+// Overlaying either sets the newer field, with the newer variable preferred.
+var envAliases = map[string]string{
+	"tenant_name": "project_name",
+	"tenant_id":   "project_id",
+}
+
+// indirections lists the auth fields that say where to find a value rather
+// than holding one, which WithEnvOverlay never sets.
+var indirections = map[string]bool{
+	"password_env":     true,
+	"password_command": true,
+	"token_file":       true,
+	"keyring_service":  true,
+	"keyring_account":  true,
+}
+
+// defaultEnvPrefix is the prefix of the environment variables WithEnvOverlay
+// reads, unless WithEnvPrefix sets another.
+const defaultEnvPrefix = "OS_"
+
+// envName returns the environment variable for a clouds.yaml field: the
+// prefix followed by the field name in upper case, such as OS_AUTH_URL.
+func envName(prefix, field string) string {
+	return prefix + strings.ToUpper(field)
+}
+
+// EnvOverride records a field of a cloud that WithEnvOverlay set from an
+// environment variable.
+type EnvOverride struct {
+	// Field is the field’s name in clouds.yaml. Fields of the auth block
+	// are prefixed with "auth.", as in auth.password.
+	Field string
+
+	// Var is the environment variable the value was read from, such as
+	// OS_PASSWORD.
+	Var string
+}
+
+// EnvOverrides satisfies the Config interface.
+func (c *configImpl) EnvOverrides(name string) ([]EnvOverride, error) {
+	v, err := c.cloud(name)
+	if err != nil {
+		return nil, err
+	}
+	return append([]EnvOverride(nil), v.envOverrides...), nil
+}
+
+// ExportEnv satisfies the Config interface.
+func (c *configImpl) ExportEnv(name string) (map[string]string, error) {
+	v, err := c.cloud(name)
+	if err != nil {
+		return nil, err
+	}
+	e := exportEntry(v)
+	env := map[string]string{}
+	for field, p := range stringFields(e.Auth) {
+		if *p != "" && !indirections[field] {
+			env[envName(defaultEnvPrefix, field)] = *p
+		}
+	}
+	cf := stringFields(e)
+	for _, field := range envCloudFields {
+		if p := cf[field]; *p != "" {
+			env[envName(defaultEnvPrefix, field)] = *p
+		}
+	}
+	if len(e.Interface) > 0 {
+		env[envName(defaultEnvPrefix, "interface")] = e.Interface[0]
+	}
+	return env, nil
+}
+
+// ExportRC satisfies the Config interface.
+func (c *configImpl) ExportRC(name string, secrets bool) ([]byte, error) {
+	env, err := c.ExportEnv(name)
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]string, 0, len(env))
+	for k := range env {
+		if secrets || !IsSecretEnvVar(k) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	var b bytes.Buffer
+	for _, k := range keys {
+		b.WriteString("export " + k + "=" + shellQuote(env[k]) + "\n")
+	}
+	return b.Bytes(), nil
+}
+
+// shellQuote quotes s for a POSIX shell, leaving it bare if it holds only
+// characters no shell treats specially.
+func shellQuote(s string) string {
+	if s != "" && strings.Trim(s, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789_-./:@%+,=") == "" {
+		return s
+	}
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
+
+// IsSecretEnvVar reports whether an environment variable ExportEnv returns
+// holds a secret, such as OS_PASSWORD, so that output meant to be shown or
+// shared can leave it out.
+func IsSecretEnvVar(name string) bool {
+	field := strings.ToLower(strings.TrimPrefix(name, defaultEnvPrefix))
+	if field == "passcode" {
+		return true
+	}
+	for _, f := range secretFields {
+		if field == f {
+			return true
+		}
+	}
+	return false
+}
+
+// overlayEnv overrides fields of a cloud entry and its auth block with the
+// values of the corresponding environment variables, named with the given
+// prefix, that are set and not empty. It returns the fields it set, sorted by
+// name.
+func overlayEnv(v *cloudYAML, a *authYAML, prefix string) []EnvOverride {
+	var set []EnvOverride
+	af := stringFields(a)
+	for field, p := range af {
+		if indirections[field] || envAliases[field] != "" {
+			continue
+		}
+		name := envName(prefix, field)
+		value := os.Getenv(name)
+		for legacy, current := range envAliases {
+			if current == field && value == "" {
+				name = envName(prefix, legacy)
+				value = os.Getenv(name)
+			}
+		}
+		if value == "" {
+			continue
+		}
+		*p = value
+		set = append(set, EnvOverride{Field: "auth." + field, Var: name})
+		for legacy, current := range envAliases {
+			if current == field {
+				*af[legacy] = ""
+			}
+		}
+	}
+	cf := stringFields(v)
+	for _, field := range envCloudFields {
+		name := envName(prefix, field)
+		if value := os.Getenv(name); value != "" {
+			*cf[field] = value
+			set = append(set, EnvOverride{Field: field, Var: name})
+		}
+	}
+	if value := os.Getenv(envName(prefix, "interface")); value != "" {
+		v.Interface = interfaceList{value}
+		set = append(set, EnvOverride{Field: "interface", Var: envName(prefix, "interface")})
+	}
+	sort.Slice(set, func(i, j int) bool { return set[i].Field < set[j].Field })
+	return set
+}
@@ -0,0 +1,267 @@
+package config
+
+import (
+	"os/exec"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestExpandEnvSinglePass(t *testing.T) {
+	t.Setenv("ENV_TEST_SELF", "${ENV_TEST_SELF}")
+	t.Setenv("ENV_TEST_OUTER", "${ENV_TEST_INNER}")
+	t.Setenv("ENV_TEST_INNER", "inner")
+	t.Setenv("ENV_TEST_DOLLAR", "pa$$word")
+	t.Setenv("ENV_TEST_UNSET", "")
+	for in, want := range map[string]string{
+		"${ENV_TEST_SELF}":                   "${ENV_TEST_SELF}",
+		"${ENV_TEST_OUTER}":                  "${ENV_TEST_INNER}",
+		"a-${ENV_TEST_INNER}-b":              "a-inner-b",
+		"${ENV_TEST_INNER}${ENV_TEST_INNER}": "innerinner",
+		"${ENV_TEST_DOLLAR}":                 "pa$$word",
+		"${ENV_TEST_UNSET}x":                 "x",
+		"$ENV_TEST_INNER":                    "$ENV_TEST_INNER",
+		"${ENV_TEST_INNER":                   "${ENV_TEST_INNER",
+		"no variables":                       "no variables",
+	} {
+		if got := expandEnv(in); got != want {
+			t.Errorf("expandEnv(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestWithEnvExpansion(t *testing.T) {
+	t.Setenv("ENV_TEST_URL", "http://a/v3")
+	t.Setenv("ENV_TEST_SELF", "${ENV_TEST_SELF}")
+	doc := []byte(`
+clouds:
+  a:
+    auth:
+      auth_url: ${ENV_TEST_URL}
+      username: u
+      password: ${ENV_TEST_SELF}
+`)
+	conf, err := FromBytes(doc, WithEnvExpansion())
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts, err := conf.Get("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opts.IdentityEndpoint != "http://a/v3" || opts.Password != "${ENV_TEST_SELF}" {
+		t.Errorf("Get with WithEnvExpansion = %+v", opts)
+	}
+
+	conf, err = FromBytes(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opts, err := conf.Get("a"); err != nil || opts.IdentityEndpoint != "${ENV_TEST_URL}" {
+		t.Errorf("Get without WithEnvExpansion = %+v, %v", opts, err)
+	}
+}
+
+func TestWithEnvPrefix(t *testing.T) {
+	t.Setenv("OS_CLOUD", "")
+	t.Setenv("OS_PASSWORD", "from-os")
+	t.Setenv("OS_REGION_NAME", "from-os")
+	t.Setenv("MYTOOL_OS_PASSWORD", "from-mytool")
+	t.Setenv("MYTOOL_OS_REGION_NAME", "RegionTwo")
+	t.Setenv("MYTOOL_OS_TENANT_NAME", "legacy-project")
+	conf, err := FromBytes([]byte(validClouds), WithEnvOverlay(), WithEnvPrefix("MYTOOL_OS_"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts, err := conf.Get("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opts.Password != "from-mytool" || opts.TenantName != "legacy-project" {
+		t.Errorf("Get(a) with a custom prefix = %+v", opts)
+	}
+	if eo, err := conf.EndpointOpts("a", ""); err != nil || eo.Region != "RegionTwo" {
+		t.Errorf("EndpointOpts(a) with a custom prefix = %+v, %v; want RegionTwo", eo, err)
+	}
+	overrides, err := conf.EnvOverrides("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	vars := map[string]bool{}
+	for _, e := range overrides {
+		vars[e.Var] = true
+	}
+	for _, v := range []string{"MYTOOL_OS_PASSWORD", "MYTOOL_OS_REGION_NAME", "MYTOOL_OS_TENANT_NAME"} {
+		if !vars[v] {
+			t.Errorf("EnvOverrides(a) = %+v, want %s among them", overrides, v)
+		}
+	}
+	if vars["OS_PASSWORD"] {
+		t.Errorf("EnvOverrides(a) = %+v, want no OS_ variables", overrides)
+	}
+}
+
+func TestExportEnv(t *testing.T) {
+	conf, err := FromBytes([]byte(`
+clouds:
+  a:
+    region_name: RegionOne
+    interface: [internal, public]
+    auth:
+      auth_url: http://a/v3
+      username: u
+      password: p
+      project_name: proj
+      user_domain_name: Default
+      project_domain_name: Default
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	env, err := conf.ExportEnv("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"OS_AUTH_URL":            "http://a/v3",
+		"OS_USERNAME":            "u",
+		"OS_PASSWORD":            "p",
+		"OS_PROJECT_NAME":        "proj",
+		"OS_USER_DOMAIN_NAME":    "Default",
+		"OS_PROJECT_DOMAIN_NAME": "Default",
+		"OS_REGION_NAME":         "RegionOne",
+		"OS_INTERFACE":           "internal",
+	}
+	if !reflect.DeepEqual(env, want) {
+		t.Errorf("ExportEnv(a) = %v, want %v", env, want)
+	}
+	for name, secret := range map[string]bool{
+		"OS_PASSWORD":                      true,
+		"OS_TOKEN":                         true,
+		"OS_APPLICATION_CREDENTIAL_SECRET": true,
+		"OS_PASSCODE":                      true,
+		"OS_USERNAME":                      false,
+		"OS_AUTH_URL":                      false,
+	} {
+		if got := IsSecretEnvVar(name); got != secret {
+			t.Errorf("IsSecretEnvVar(%s) = %v, want %v", name, got, secret)
+		}
+	}
+}
+
+func TestExportEnvRoundTrip(t *testing.T) {
+	conf, err := FromBytes([]byte(`
+clouds:
+  a:
+    region_name: RegionOne
+    auth:
+      auth_url: http://a/v3
+      username: u
+      password: p
+      project_name: proj
+      user_domain_name: Default
+      project_domain_name: Default
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := conf.Get("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	env, err := conf.ExportEnv("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// With no clouds.yaml to find, LoadEffective builds the cloud from
+	// the exported variables alone.
+	isolateSearch(t)
+	for k, v := range env {
+		t.Setenv(k, v)
+	}
+	got, err := LoadEffective("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("LoadEffective from ExportEnv = %+v, want %+v", got, want)
+	}
+}
+
+func TestExportRC(t *testing.T) {
+	conf, err := FromBytes([]byte(`
+clouds:
+  a:
+    region_name: Region One
+    auth:
+      auth_url: http://a/v3
+      username: "o'brien"
+      password: "p@ss $word"
+      project_name: "proj;rm -rf"
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rc, err := conf.ExportRC("a", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `export OS_AUTH_URL=http://a/v3
+export OS_PASSWORD='p@ss $word'
+export OS_PROJECT_NAME='proj;rm -rf'
+export OS_REGION_NAME='Region One'
+export OS_USERNAME='o'\''brien'
+`
+	if string(rc) != want {
+		t.Errorf("ExportRC(a, true) =\n%s\nwant\n%s", rc, want)
+	}
+
+	rc, err = conf.ExportRC("a", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(rc), "OS_PASSWORD") {
+		t.Errorf("ExportRC(a, false) =\n%s\nwant no OS_PASSWORD", rc)
+	}
+}
+
+func TestExportRCSourced(t *testing.T) {
+	sh, err := exec.LookPath("sh")
+	if err != nil {
+		t.Skip("no sh")
+	}
+	values := map[string]string{
+		"OS_USERNAME":     "o'brien",
+		"OS_PASSWORD":     `p@ss $word "quoted" \back`,
+		"OS_REGION_NAME":  "Region One",
+		"OS_PROJECT_NAME": "proj;echo pwned",
+	}
+	conf, err := FromBytes([]byte(`
+clouds:
+  a:
+    region_name: "Region One"
+    auth:
+      auth_url: http://a/v3
+      username: "o'brien"
+      password: 'p@ss $word "quoted" \back'
+      project_name: "proj;echo pwned"
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rc, err := conf.ExportRC("a", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range values {
+		script := string(rc) + "printf %s \"$" + name + "\"\n"
+		out, err := exec.Command(sh, "-c", script).Output()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(out) != want {
+			t.Errorf("sourced %s = %q, want %q", name, out, want)
+		}
+	}
+}
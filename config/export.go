@@ -0,0 +1,137 @@
+package config
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"github.com/gophercloud/gophercloud"
+	"gopkg.in/yaml.v2"
+)
+
+// redactedValue replaces secrets in redacted output.
+const redactedValue = "<redacted>"
+
+// ExportCloud satisfies the Config interface.
+func (c *configImpl) ExportCloud(name string) ([]byte, error) {
+	v, err := c.cloud(name)
+	if err != nil {
+		return nil, err
+	}
+	return exportCloud(name, v)
+}
+
+// ExportCloudRedacted satisfies the Config interface.
+func (c *configImpl) ExportCloudRedacted(name string) ([]byte, error) {
+	v, err := c.cloud(name)
+	if err != nil {
+		return nil, err
+	}
+	v.auth = redact(v.auth)
+	v.headers = v.redactedHeaders()
+	return exportCloud(name, v)
+}
+
+// Fingerprint satisfies the Config interface.
+func (c *configImpl) Fingerprint(name string) (string, error) {
+	v, err := c.cloud(name)
+	if err != nil {
+		return "", err
+	}
+	// The export is deterministic, as its fields are in a fixed order, so
+	// it serves as the normalized form. The name is left out so that
+	// identical clouds get the same fingerprint.
+	b, err := exportCloud("", v)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// AuthMethods satisfies the Config interface.
+func (c *configImpl) AuthMethods(name string) ([]string, error) {
+	v, err := c.cloud(name)
+	if err != nil {
+		return nil, err
+	}
+	return append([]string(nil), v.authMethods...), nil
+}
+
+// exportCloud marshals a single cloud as a clouds.yaml document.
+func exportCloud(name string, v cloud) ([]byte, error) {
+	doc := map[string]map[string]*cloudYAML{"clouds": {name: exportEntry(v)}}
+	b, err := yaml.Marshal(doc)
+	if err != nil {
+		return nil, errors.New("config: " + err.Error())
+	}
+	return b, nil
+}
+
+// exportEntry converts a cloud back to a clouds.yaml entry.
+func exportEntry(v cloud) *cloudYAML {
+	e := &cloudYAML{
+		Auth:        newAuthYAML(v.auth),
+		AuthType:    v.authType,
+		RegionName:  v.region,
+		Regions:     exportRegions(v.regions, v.region, v.regionIfaces),
+		Description: v.description,
+		Interface:   v.ifaces,
+		CACert:      v.tls.cacert,
+		CAPath:      v.tls.capath,
+		Cert:        v.tls.cert,
+		Key:         v.tls.key,
+		ServerName:  v.tls.serverName,
+
+		ConnectRetries:    v.retry.ConnectRetries,
+		ReadRetries:       v.retry.ReadRetries,
+		ConnectRetryDelay: v.retry.Backoff.Seconds(),
+
+		ComputeAPIVersion:  v.apiVersions["compute"],
+		IdentityAPIVersion: v.apiVersions["identity"],
+		ImageAPIVersion:    v.apiVersions["image"],
+		NetworkAPIVersion:  v.apiVersions["network"],
+		VolumeAPIVersion:   v.apiVersions["volume"],
+
+		Extra: v.extra,
+	}
+	e.Auth.AuthMethods = v.authMethods
+	e.Auth.ProjectDomainID = v.projectDomainID()
+	e.Auth.ProjectDomainName = v.projectDomainName()
+	e.Auth.Endpoint = v.endpoint
+	e.Headers = v.headers
+	if v.tls.insecure {
+		verify := false
+		e.Verify = &verify
+	}
+	return e
+}
+
+// exportRegions returns a cloud’s regions as a regions list, with the
+// interfaces set for them, or nil if its only region is the one given by
+// region_name and sets no interface.
+func exportRegions(regions []string, region string, ifaces map[string][]string) []regionYAML {
+	if len(regions) == 0 || len(regions) == 1 && regions[0] == region && len(ifaces) == 0 {
+		return nil
+	}
+	rs := make([]regionYAML, len(regions))
+	for i, r := range regions {
+		rs[i] = regionYAML{Name: r, Interface: ifaces[r]}
+	}
+	return rs
+}
+
+// redact returns a copy of opts with every non-empty secret masked.
+func redact(opts gophercloud.AuthOptions) gophercloud.AuthOptions {
+	opts = cloneAuthOptions(opts)
+	for _, s := range []*string{
+		&opts.Password,
+		&opts.Passcode,
+		&opts.TokenID,
+		&opts.ApplicationCredentialSecret,
+	} {
+		if *s != "" {
+			*s = redactedValue
+		}
+	}
+	return opts
+}
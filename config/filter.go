@@ -0,0 +1,57 @@
+package config
+
+import (
+	"errors"
+	"github.com/gophercloud/gophercloud"
+)
+
+// Filter satisfies the Config interface.
+func (c *configImpl) Filter(pred func(name string, opts gophercloud.AuthOptions) bool) Config {
+	// The clouds are copied out before pred runs, so a predicate may call
+	// back into the Config without deadlocking against a waiting writer.
+	c.mu.RLock()
+	all := make(map[string]cloud, len(c.clouds))
+	for k, v := range c.clouds {
+		all[k] = v
+	}
+	f := &configImpl{
+		defaultCloud: c.defaultCloud,
+		order:        c.order,
+		reloadTokens: c.reloadTokens,
+		prompt:       c.prompt,
+		providers:    c.providers,
+	}
+	if c.cache != nil {
+		f.cache = newClientCache()
+	}
+	c.mu.RUnlock()
+	cs := map[string]cloud{}
+	for k, v := range all {
+		if pred(k, cloneAuthOptions(v.auth)) {
+			cs[k] = v
+		}
+	}
+	f.clouds = cs
+	return f
+}
+
+// Only satisfies the Config interface.
+func (c *configImpl) Only(name string) (Config, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	v, ok := c.clouds[name]
+	if !ok || v.disabled {
+		return nil, errors.New("config: cloud `" + name + "` not found")
+	}
+	o := &configImpl{
+		clouds:       map[string]cloud{name: v},
+		defaultCloud: name,
+		reloadTokens: c.reloadTokens,
+		prompt:       c.prompt,
+		providers:    c.providers,
+	}
+	if c.cache != nil {
+		o.cache = newClientCache()
+	}
+	return o, nil
+}
@@ -0,0 +1,31 @@
+package config
+
+import (
+	"github.com/gophercloud/gophercloud"
+	"testing"
+	"time"
+)
+
+func TestFilterPredicateMayCallConfig(t *testing.T) {
+	c := FromMap(map[string]gophercloud.AuthOptions{
+		"a": {IdentityEndpoint: "http://a"},
+		"b": {IdentityEndpoint: "http://b"},
+	})
+	done := make(chan Config)
+	go func() {
+		done <- c.Filter(func(name string, _ gophercloud.AuthOptions) bool {
+			// Taking the write lock from the predicate deadlocks if Filter
+			// still holds the read lock.
+			c.Merge(FromMap(map[string]gophercloud.AuthOptions{}))
+			return name == "a"
+		})
+	}()
+	select {
+	case f := <-done:
+		if names := f.Names(); len(names) != 1 || names[0] != "a" {
+			t.Errorf("Names() = %v, want [a]", names)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Filter deadlocked calling back into the Config")
+	}
+}
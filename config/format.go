@@ -0,0 +1,61 @@
+package config
+
+import (
+	"encoding/json"
+	"gopkg.in/yaml.v2"
+	"strconv"
+)
+
+// Format is the syntax of a config document, as chosen by WithFormat.
+type Format int
+
+const (
+	// FormatAuto, the default, detects the syntax: content is parsed as
+	// YAML, which includes JSON, and if that fails and TOML support is
+	// built in, as TOML. If neither succeeds, the YAML error is reported.
+	// File names and extensions play no part in detection.
+	FormatAuto Format = iota
+
+	// FormatYAML parses content as YAML only.
+	FormatYAML
+
+	// FormatJSON parses content as JSON only, rejecting YAML that is not
+	// JSON.
+	FormatJSON
+
+	// FormatTOML parses content as TOML only, using the same schema as
+	// clouds.yaml (see FromTOML). It is only supported with the toml build
+	// tag.
+	FormatTOML
+)
+
+// String returns the format’s name, such as "yaml".
+func (f Format) String() string {
+	switch f {
+	case FormatAuto:
+		return "auto"
+	case FormatYAML:
+		return "yaml"
+	case FormatJSON:
+		return "json"
+	case FormatTOML:
+		return "toml"
+	}
+	return "Format(" + strconv.Itoa(int(f)) + ")"
+}
+
+// toYAML converts content in the format WithFormat forces to YAML, for the
+// clouds.yaml parser. Content in FormatAuto or FormatYAML is returned as-is.
+func toYAML(b []byte, o *options) ([]byte, error) {
+	switch o.format {
+	case FormatJSON:
+		var doc interface{}
+		if err := json.Unmarshal(b, &doc); err != nil {
+			return nil, err
+		}
+		return yaml.Marshal(doc)
+	case FormatTOML:
+		return tomlToYAML(b)
+	}
+	return b, nil
+}
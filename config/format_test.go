@@ -0,0 +1,88 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+const jsonClouds = `{"clouds": {"a": {"auth": {"auth_url": "http://a/v3", "username": "ua", "password": "pa"}}}}`
+
+func TestWithFormat(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		content string
+		format  Format
+		wantErr string
+		// parseErr is whether the error is a *ParseError, as opposed to
+		// one from the option itself.
+		parseErr bool
+	}{
+		{name: "auto yaml", content: validClouds, format: FormatAuto},
+		{name: "auto json", content: jsonClouds, format: FormatAuto},
+		{name: "yaml", content: validClouds, format: FormatYAML},
+		{name: "yaml accepts json", content: jsonClouds, format: FormatYAML},
+		{name: "json", content: jsonClouds, format: FormatJSON},
+		{name: "json rejects yaml", content: validClouds, format: FormatJSON, wantErr: "invalid character", parseErr: true},
+		{name: "below range", content: validClouds, format: FormatAuto - 1, wantErr: "config: unknown format Format(-1)"},
+		{name: "above range", content: validClouds, format: FormatTOML + 1, wantErr: "config: unknown format Format(4)"},
+	} {
+		conf, err := FromBytes([]byte(tc.content), WithFormat(tc.format))
+		if tc.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Errorf("%s: FromBytes error = %v, want it to mention %q", tc.name, err, tc.wantErr)
+			}
+			if _, ok := err.(*ParseError); ok != tc.parseErr {
+				t.Errorf("%s: FromBytes error = %T, want a *ParseError: %v", tc.name, err, tc.parseErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: FromBytes: %v", tc.name, err)
+			continue
+		}
+		if opts, err := conf.Get("a"); err != nil || opts.IdentityEndpoint != "http://a/v3" || opts.Password != "pa" {
+			t.Errorf("%s: Get(a) = %+v, %v; want cloud a", tc.name, opts, err)
+		}
+	}
+}
+
+func TestWithFormatSecure(t *testing.T) {
+	const clouds = `{"clouds": {"a": {"auth": {"auth_url": "http://a/v3", "username": "ua"}}}}`
+	for _, tc := range []struct {
+		name    string
+		secure  string
+		wantErr bool
+	}{
+		{name: "json", secure: `{"clouds": {"a": {"auth": {"password": "pa"}}}}`},
+		{name: "yaml", secure: "clouds:\n  a:\n    auth: {password: pa}\n", wantErr: true},
+	} {
+		conf, err := FromReaders(strings.NewReader(clouds), strings.NewReader(tc.secure), WithFormat(FormatJSON))
+		if tc.wantErr {
+			if _, ok := err.(*ParseError); !ok {
+				t.Errorf("%s secure.yaml with FormatJSON error = %v, want a *ParseError", tc.name, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s secure.yaml with FormatJSON: %v", tc.name, err)
+			continue
+		}
+		if opts, err := conf.Get("a"); err != nil || opts.Password != "pa" {
+			t.Errorf("%s secure.yaml with FormatJSON: Get(a) = %+v, %v; want the secure password", tc.name, opts, err)
+		}
+	}
+}
+
+func TestFormatString(t *testing.T) {
+	for f, want := range map[Format]string{
+		FormatAuto:     "auto",
+		FormatYAML:     "yaml",
+		FormatJSON:     "json",
+		FormatTOML:     "toml",
+		FormatTOML + 1: "Format(4)",
+	} {
+		if got := f.String(); got != want {
+			t.Errorf("Format(%d).String() = %q, want %q", int(f), got, want)
+		}
+	}
+}
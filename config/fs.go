@@ -0,0 +1,55 @@
+package config
+
+import (
+	"errors"
+	"io/fs"
+	"path"
+)
+
+// NewFromFS is like New, but searches the directories dirs of fsys, such as
+// an embed.FS bundling several candidate configurations, rather than the
+// real filesystem. The directories are searched in the order given, and the
+// clouds.yaml file in the first that holds a usable one wins, with the same
+// rules as New for skipping files and stopping the search: a directory with
+// no clouds.yaml file, or an empty one, is skipped, a malformed file stops
+// the search with a *ParseError, and a file that cannot be read for lack of
+// permission stops it with a *FileError.
+//
+// As usual for an fs.FS, directories are slash-separated paths relative to
+// the root of fsys, without a leading slash, and "." names the root itself.
+// Errors name files by the same paths, such as conf/prod/clouds.yaml. File
+// paths inside clouds.yaml, such as cacert, still refer to the real
+// filesystem, so relative ones are left as written rather than being taken
+// relative to the directory in fsys.
+func NewFromFS(fsys fs.FS, dirs []string, opts ...Option) (Config, error) {
+	o, err := newOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+	for _, d := range dirs {
+		if !fs.ValidPath(d) {
+			return nil, errors.New("config: invalid directory `" + d + "` in filesystem")
+		}
+		p := path.Join(d, "clouds.yaml")
+		f, err := fsys.Open(p)
+		if err != nil {
+			if errors.Is(err, fs.ErrPermission) {
+				return nil, &FileError{p, err}
+			}
+			continue
+		}
+		b, err := readAll(f, p, o)
+		f.Close()
+		if err != nil {
+			return nil, err
+		}
+		conf, err := parseWithSecure(b, p, nil, "", "", o)
+		if err == nil {
+			return conf, nil
+		}
+		if parseErr, ok := err.(*ParseError); ok && parseErr.Err != ErrEmptyConfig {
+			return nil, parseErr
+		}
+	}
+	return nil, errNoConfigFile
+}
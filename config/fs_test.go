@@ -0,0 +1,34 @@
+package config
+
+import (
+	"errors"
+	"testing"
+	"testing/fstest"
+)
+
+func TestNewFromFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"empty/clouds.yaml": {Data: []byte("# nothing yet\n")},
+		"prod/clouds.yaml":  {Data: []byte("clouds:\n  prod:\n    auth: {auth_url: http://prod/v3}\n")},
+		"bad/clouds.yaml":   {Data: []byte("clouds: [\n")},
+	}
+	c, err := NewFromFS(fsys, []string{"missing", "empty", "prod", "bad"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if names := c.Names(); len(names) != 1 || names[0] != "prod" {
+		t.Errorf("Names() = %v, want [prod]", names)
+	}
+
+	_, err = NewFromFS(fsys, []string{"bad", "prod"})
+	var parseErr *ParseError
+	if !errors.As(err, &parseErr) || parseErr.File != "bad/clouds.yaml" {
+		t.Errorf("NewFromFS(bad first) = %v, want a *ParseError for bad/clouds.yaml", err)
+	}
+	if _, err := NewFromFS(fsys, []string{"empty", "missing"}); err != errNoConfigFile {
+		t.Errorf("NewFromFS(nothing usable) = %v, want %v", err, errNoConfigFile)
+	}
+	if _, err := NewFromFS(fsys, []string{"/prod"}); err == nil {
+		t.Error("NewFromFS(/prod) = nil error, want an invalid path error")
+	}
+}
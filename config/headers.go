@@ -0,0 +1,57 @@
+package config
+
+import (
+	"net/http"
+	"strings"
+)
+
+// headerTransport adds a cloud’s headers to each request it sends, unless the
+// request already sets them.
+type headerTransport struct {
+	base    http.RoundTripper
+	headers map[string]string
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *headerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// A RoundTripper must not change the request it is given.
+	req = req.Clone(req.Context())
+	for k, v := range t.headers {
+		if req.Header.Get(k) == "" {
+			req.Header.Set(k, v)
+		}
+	}
+	base := t.base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return base.RoundTrip(req)
+}
+
+// secretHeaderWords are the parts of header names that suggest the header
+// holds a secret.
+var secretHeaderWords = []string{"auth", "cookie", "key", "password", "secret", "token"}
+
+// redactedHeaders returns a copy of the cloud’s headers with the value of
+// every header marked by WithSecretHeaders, or whose name suggests a secret,
+// masked.
+func (v cloud) redactedHeaders() map[string]string {
+	if v.headers == nil {
+		return nil
+	}
+	m := make(map[string]string, len(v.headers))
+	for k, value := range v.headers {
+		m[k] = value
+		if v.secretHdrs[http.CanonicalHeaderKey(k)] {
+			m[k] = redactedValue
+			continue
+		}
+		for _, w := range secretHeaderWords {
+			if strings.Contains(strings.ToLower(k), w) {
+				m[k] = redactedValue
+				break
+			}
+		}
+	}
+	return m
+}
@@ -0,0 +1,86 @@
+package config
+
+import (
+	"github.com/gophercloud/gophercloud"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const headerClouds = `
+clouds:
+  a:
+    auth_type: admin_token
+    headers:
+      X-Api-Key: k3y
+      X-Tenant-Hint: blue
+      X-Gateway-Pass: pa55
+    auth:
+      token: tok
+      endpoint: ENDPOINT
+`
+
+func TestHeadersSent(t *testing.T) {
+	var got http.Header
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Clone()
+	}))
+	defer srv.Close()
+	conf, err := FromBytes([]byte(strings.Replace(headerClouds, "ENDPOINT", srv.URL, 1)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := conf.Authenticate("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = p.Request(http.MethodGet, srv.URL, &gophercloud.RequestOpts{
+		OkCodes:     []int{http.StatusOK},
+		MoreHeaders: map[string]string{"X-Tenant-Hint": "red"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for k, want := range map[string]string{
+		"X-Api-Key":      "k3y",
+		"X-Gateway-Pass": "pa55",
+		"X-Tenant-Hint":  "red",
+		"X-Auth-Token":   "tok",
+	} {
+		if v := got.Get(k); v != want {
+			t.Errorf("request header %s = %q, want %q", k, v, want)
+		}
+	}
+}
+
+func TestHeadersRedacted(t *testing.T) {
+	conf, err := FromBytes([]byte(strings.Replace(headerClouds, "ENDPOINT", "http://a/", 1)), WithSecretHeaders("x-gateway-pass"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := conf.ExportCloudRedacted("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := string(b)
+	for _, secret := range []string{"k3y", "pa55"} {
+		if strings.Contains(s, secret) {
+			t.Errorf("ExportCloudRedacted(a) =\n%s\nwant %s masked", s, secret)
+		}
+	}
+	if !strings.Contains(s, "blue") {
+		t.Errorf("ExportCloudRedacted(a) =\n%s\nwant X-Tenant-Hint shown", s)
+	}
+
+	conf, err = FromBytes([]byte(strings.Replace(headerClouds, "ENDPOINT", "http://a/", 1)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b, err = conf.ExportCloudRedacted("a"); err != nil {
+		t.Fatal(err)
+	}
+	if s := string(b); strings.Contains(s, "k3y") || !strings.Contains(s, "pa55") {
+		t.Errorf("ExportCloudRedacted(a) without WithSecretHeaders =\n%s\nwant only X-Api-Key masked", s)
+	}
+}
@@ -0,0 +1,113 @@
+package config
+
+import (
+	"errors"
+	"github.com/gophercloud/gophercloud"
+)
+
+// interfaceList is a cloud entry’s interface field: one interface name, or a
+// list of them in order of preference.
+type interfaceList []string
+
+// UnmarshalYAML implements yaml.Unmarshaler.
+func (l *interfaceList) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var s string
+	if err := unmarshal(&s); err == nil {
+		*l = nil
+		if s != "" {
+			*l = interfaceList{s}
+		}
+		return nil
+	}
+	var ss []string
+	if err := unmarshal(&ss); err != nil {
+		return errors.New("interface must be a name or a list of names")
+	}
+	*l = ss
+	return nil
+}
+
+// MarshalYAML implements yaml.Marshaler.
+func (l interfaceList) MarshalYAML() (interface{}, error) {
+	if len(l) == 1 {
+		return l[0], nil
+	}
+	return []string(l), nil
+}
+
+// InterfacePreference satisfies the Config interface.
+func (c *configImpl) InterfacePreference(name string) ([]gophercloud.Availability, error) {
+	v, err := c.cloud(name)
+	if err != nil {
+		return nil, err
+	}
+	if len(v.interfaces(v.region)) == 0 {
+		return nil, nil
+	}
+	avs, err := v.availabilities(v.region)
+	if err != nil {
+		return nil, errors.New("config: cloud `" + name + "`: " + err.Error())
+	}
+	return avs, nil
+}
+
+// EndpointOpts satisfies the Config interface.
+func (c *configImpl) EndpointOpts(name, region string) (gophercloud.EndpointOpts, error) {
+	v, err := c.cloud(name)
+	if err != nil {
+		return gophercloud.EndpointOpts{}, err
+	}
+	return v.endpointOpts(name, region)
+}
+
+// ClientConfig satisfies the Config interface.
+func (c *configImpl) ClientConfig(name string) (gophercloud.AuthOptions, gophercloud.EndpointOpts, error) {
+	v, err := c.cloudWithSecrets(name)
+	if err != nil {
+		return gophercloud.AuthOptions{}, gophercloud.EndpointOpts{}, err
+	}
+	eo, err := v.endpointOpts(name, "")
+	if err != nil {
+		return gophercloud.AuthOptions{}, gophercloud.EndpointOpts{}, err
+	}
+	return cloneAuthOptions(v.auth), eo, nil
+}
+
+// endpointOpts implements EndpointOpts for the cloud with the given name.
+func (v cloud) endpointOpts(name, region string) (gophercloud.EndpointOpts, error) {
+	region = firstNonEmpty(region, v.region)
+	avs, err := v.availabilities(region)
+	if err != nil {
+		return gophercloud.EndpointOpts{}, errors.New("config: cloud `" + name + "`: " + err.Error())
+	}
+	return gophercloud.EndpointOpts{Region: region, Availability: avs[0]}, nil
+}
+
+// interfaces returns the interface names the cloud prefers in a region: those
+// set for the region in its regions list, or else its own.
+func (v cloud) interfaces(region string) []string {
+	if ifaces := v.regionIfaces[region]; len(ifaces) > 0 {
+		return ifaces
+	}
+	return v.ifaces
+}
+
+// availabilities returns the catalog interfaces to select the cloud’s service
+// endpoints in a region from, in order of preference. If the cloud names none
+// there, this returns a single empty Availability, leaving the choice to
+// gophercloud.
+func (v cloud) availabilities(region string) ([]gophercloud.Availability, error) {
+	ifaces := v.interfaces(region)
+	if len(ifaces) == 0 {
+		return []gophercloud.Availability{""}, nil
+	}
+	avs := make([]gophercloud.Availability, len(ifaces))
+	for i, iface := range ifaces {
+		a, err := availability(iface)
+		if err != nil {
+			return nil, err
+		}
+		avs[i] = a
+	}
+	return avs, nil
+}
@@ -0,0 +1,76 @@
+package config
+
+import (
+	"github.com/gophercloud/gophercloud"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestInterfacePreference(t *testing.T) {
+	conf, err := FromBytes([]byte(`
+clouds:
+  scalar:
+    interface: internal
+    auth: {auth_url: http://a/v3}
+  legacy:
+    interface: internalURL
+    auth: {auth_url: http://a/v3}
+  list:
+    interface: [internal, public]
+    auth: {auth_url: http://a/v3}
+  unset:
+    auth: {auth_url: http://a/v3}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string][]gophercloud.Availability{
+		"scalar": {gophercloud.AvailabilityInternal},
+		"legacy": {gophercloud.AvailabilityInternal},
+		"list":   {gophercloud.AvailabilityInternal, gophercloud.AvailabilityPublic},
+		"unset":  nil,
+	} {
+		got, err := conf.InterfacePreference(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("InterfacePreference(%s) = %v, want %v", name, got, want)
+		}
+	}
+
+	_, err = FromBytes([]byte("clouds:\n  a:\n    interface: {internal: true}\n    auth: {auth_url: http://a/v3}\n"))
+	if err == nil || !strings.Contains(err.Error(), "interface must be a name or a list of names") {
+		t.Errorf("FromBytes with a mapping interface error = %v", err)
+	}
+}
+
+func TestServiceClientInterfaceFallback(t *testing.T) {
+	authURL := fakeKeystone(t)
+	conf, err := FromBytes([]byte(`
+clouds:
+  a:
+    region_name: RegionOne
+    interface: [admin, internal, public]
+    auth: {auth_url: ` + authURL + `, username: u, password: p, user_domain_name: Default}
+  b:
+    region_name: RegionOne
+    interface: [admin]
+    auth: {auth_url: ` + authURL + `, username: u, password: p, user_domain_name: Default}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The catalog has no admin endpoints, so a falls back to internal.
+	sc, err := conf.ServiceClient("a", "compute")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := strings.TrimSuffix(authURL, "/v3") + "/RegionOne/internal/compute/v2.1/"; sc.Endpoint != want {
+		t.Errorf("ServiceClient(a) endpoint = %s, want %s", sc.Endpoint, want)
+	}
+	if _, err := conf.ServiceClient("b", "compute"); err == nil {
+		t.Error("ServiceClient(b) with no matching interface succeeded")
+	}
+}
@@ -0,0 +1,26 @@
+//go:build keyring
+// +build keyring
+
+package config
+
+import (
+	"errors"
+	"github.com/zalando/go-keyring"
+)
+
+// keyringGet reads a secret from the system keyring; tests replace it.
+var keyringGet = keyring.Get
+
+// keyringSecret returns the secret stored in the system keyring for a service
+// and account.
+func keyringSecret(service, account string) (string, error) {
+	secret, err := keyringGet(service, account)
+	if err == keyring.ErrNotFound {
+		s := "keyring has no secret for service `" + service + "` and account `" + account + "`"
+		return "", errors.New(s)
+	}
+	if err != nil {
+		return "", errors.New("cannot read keyring: " + err.Error())
+	}
+	return secret, nil
+}
@@ -0,0 +1,13 @@
+//go:build !keyring
+// +build !keyring
+
+package config
+
+import (
+	"errors"
+)
+
+// keyringSecret reports that keyring support was not built in.
+func keyringSecret(service, account string) (string, error) {
+	return "", errors.New("keyring support is not built in; build with the keyring tag")
+}
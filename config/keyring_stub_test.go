@@ -0,0 +1,19 @@
+//go:build !keyring
+// +build !keyring
+
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestKeyringNotBuiltIn(t *testing.T) {
+	_, err := FromBytes([]byte("clouds:\n  a:\n    auth: {auth_url: http://a/v3, application_credential_id: ac, keyring_service: openstack}\n"))
+	if _, ok := err.(*ParseError); !ok {
+		t.Fatalf("FromBytes with keyring_service error = %v, want a *ParseError", err)
+	}
+	if !strings.Contains(err.Error(), "cloud `a`: keyring support is not built in") {
+		t.Errorf("FromBytes with keyring_service error = %v, want it to say keyring support is not built in", err)
+	}
+}
@@ -0,0 +1,69 @@
+//go:build keyring
+// +build keyring
+
+package config
+
+import (
+	"errors"
+	"github.com/zalando/go-keyring"
+	"strings"
+	"testing"
+)
+
+// fakeKeyring replaces the system keyring for the test with the given
+// entries, keyed by service and account joined with a slash.
+func fakeKeyring(t *testing.T, entries map[string]string) {
+	t.Helper()
+	get := keyringGet
+	t.Cleanup(func() { keyringGet = get })
+	keyringGet = func(service, account string) (string, error) {
+		if secret, ok := entries[service+"/"+account]; ok {
+			return secret, nil
+		}
+		return "", keyring.ErrNotFound
+	}
+}
+
+func TestKeyringSecret(t *testing.T) {
+	fakeKeyring(t, map[string]string{
+		"openstack/a":    "secret-a",
+		"openstack/prod": "secret-prod",
+	})
+	conf, err := FromBytes([]byte(`
+clouds:
+  a:
+    auth: {auth_url: http://a/v3, application_credential_id: ac, keyring_service: openstack}
+  b:
+    auth: {auth_url: http://b/v3, application_credential_id: bc, keyring_service: openstack, keyring_account: prod}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]string{"a": "secret-a", "b": "secret-prod"} {
+		if opts, err := conf.Get(name); err != nil || opts.ApplicationCredentialSecret != want {
+			t.Errorf("Get(%s) = %+v, %v; want the keyring secret %s", name, opts, err, want)
+		}
+	}
+}
+
+func TestKeyringSecretMissing(t *testing.T) {
+	fakeKeyring(t, map[string]string{"openstack/other": "secret-other"})
+	_, err := FromBytes([]byte("clouds:\n  a:\n    auth: {auth_url: http://a/v3, application_credential_id: ac, keyring_service: openstack}\n"))
+	if _, ok := err.(*ParseError); !ok {
+		t.Fatalf("FromBytes with a missing keyring entry error = %v, want a *ParseError", err)
+	}
+	want := "cloud `a`: keyring has no secret for service `openstack` and account `a`"
+	if !strings.Contains(err.Error(), want) {
+		t.Errorf("FromBytes with a missing keyring entry error = %v, want it to mention %s", err, want)
+	}
+	if strings.Contains(err.Error(), "secret-other") {
+		t.Errorf("FromBytes with a missing keyring entry error = %v, leaks a keyring secret", err)
+	}
+
+	keyringGet = func(service, account string) (string, error) {
+		return "", errors.New("locked")
+	}
+	if _, err := FromBytes([]byte("clouds:\n  a:\n    auth: {auth_url: http://a/v3, keyring_service: openstack}\n")); err == nil || !strings.Contains(err.Error(), "cannot read keyring: locked") {
+		t.Errorf("FromBytes with an unreadable keyring error = %v, want it to say the keyring cannot be read", err)
+	}
+}
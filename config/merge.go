@@ -0,0 +1,42 @@
+package config
+
+// Merge satisfies the Config interface.
+func (c *configImpl) Merge(other Config) (added, replaced int) {
+	// Copy other’s clouds before locking c, so merging a Config into
+	// itself doesn’t deadlock.
+	incoming := clouds(other)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	merged := make(map[string]cloud, len(c.clouds)+len(incoming))
+	for k, v := range c.clouds {
+		merged[k] = v
+	}
+	for k, v := range incoming {
+		if _, ok := merged[k]; ok {
+			replaced++
+		} else {
+			added++
+		}
+		merged[k] = v
+	}
+	c.clouds = merged
+	return added, replaced
+}
+
+// clouds returns a copy of every cloud in a Config, including disabled ones.
+// For Configs not created by this package, only the AuthOptions are known.
+func clouds(conf Config) map[string]cloud {
+	cs := map[string]cloud{}
+	if c, ok := conf.(*configImpl); ok {
+		c.mu.RLock()
+		defer c.mu.RUnlock()
+		for k, v := range c.clouds {
+			cs[k] = v
+		}
+		return cs
+	}
+	for k, v := range conf.AllIncludingDisabled() {
+		cs[k] = cloud{auth: v}
+	}
+	return cs
+}
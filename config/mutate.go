@@ -0,0 +1,67 @@
+package config
+
+import (
+	"errors"
+	"github.com/gophercloud/gophercloud"
+)
+
+// Add satisfies the Config interface.
+func (c *configImpl) Add(name string, opts gophercloud.AuthOptions) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.clouds[name]; ok {
+		return errors.New("config: cloud `" + name + "` already exists")
+	}
+	clouds := c.copyClouds()
+	clouds[name] = cloud{auth: cloneAuthOptions(opts)}
+	c.clouds = clouds
+	return nil
+}
+
+// Remove satisfies the Config interface.
+func (c *configImpl) Remove(name string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.clouds[name]; !ok {
+		return errors.New("config: cloud `" + name + "` not found")
+	}
+	clouds := c.copyClouds()
+	delete(clouds, name)
+	c.clouds = clouds
+	return nil
+}
+
+// Snapshot is a point-in-time copy of a Config’s clouds, taken by
+// Config.Snapshot and reinstated by Config.Restore.
+type Snapshot struct {
+	clouds       map[string]cloud
+	defaultCloud string
+}
+
+// Snapshot satisfies the Config interface.
+func (c *configImpl) Snapshot() Snapshot {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return Snapshot{clouds: c.copyClouds(), defaultCloud: c.defaultCloud}
+}
+
+// Restore satisfies the Config interface.
+func (c *configImpl) Restore(s Snapshot) {
+	clouds := make(map[string]cloud, len(s.clouds))
+	for k, v := range s.clouds {
+		clouds[k] = v
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.clouds = clouds
+	c.defaultCloud = s.defaultCloud
+}
+
+// copyClouds returns a copy of the map of clouds. The caller must hold c.mu.
+func (c *configImpl) copyClouds() map[string]cloud {
+	clouds := make(map[string]cloud, len(c.clouds))
+	for k, v := range c.clouds {
+		clouds[k] = v
+	}
+	return clouds
+}
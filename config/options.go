@@ -0,0 +1,436 @@
+package config
+
+import (
+	"errors"
+	"github.com/gophercloud/gophercloud"
+	"net/http"
+	"time"
+)
+
+// Option configures how a Config is loaded. Options are accepted by New and
+// the From* constructors.
+type Option func(*options) error
+
+// options holds the settings applied by Options.
+type options struct {
+	cloudsKey        string
+	userConfigDir    string
+	expandEnv        bool
+	cache            bool
+	noDups           bool
+	noUnknown        bool
+	envOverlay       bool
+	secretDir        string
+	commandTimeout   time.Duration
+	maxSize          int64
+	reloadTokens     bool
+	strictDiscovery  bool
+	envPrefix        string
+	publicCloudsPath string
+	publicCloudsURL  string
+	prompt           func(cloud, field string) (string, error)
+	providers        []CredentialProvider
+	format           Format
+	homeDirFunc      func() (string, error)
+	watchInterval    time.Duration
+	minIdentity      string
+	disabledAsEmpty  bool
+	regionAliases    map[string]string
+	secretHeaders    map[string]bool
+	defaultAuthType  string
+	secureFile       bool
+	overlayCloud     string
+	transforms       []func(string, gophercloud.AuthOptions) gophercloud.AuthOptions
+}
+
+// newOptions returns the default settings with opts applied in order.
+func newOptions(opts []Option) (*options, error) {
+	o := &options{
+		cloudsKey:      "clouds",
+		userConfigDir:  ".config/openstack",
+		commandTimeout: defaultCommandTimeout,
+		maxSize:        defaultMaxSize,
+		envPrefix:      defaultEnvPrefix,
+		watchInterval:  defaultWatchInterval,
+	}
+	for _, opt := range opts {
+		if err := opt(o); err != nil {
+			return nil, err
+		}
+	}
+	return o, nil
+}
+
+// WithCloudsKey sets the top-level key under which clouds are defined, for
+// documents that nest OpenStack configuration under a key of their own. The
+// default, clouds, is the key OpenStack tools expect.
+func WithCloudsKey(key string) Option {
+	return func(o *options) error {
+		o.cloudsKey = key
+		return nil
+	}
+}
+
+// WithUserConfigDir sets the directory, relative to the user’s home
+// directory, that New searches for clouds.yaml in place of the default,
+// .config/openstack.
+func WithUserConfigDir(subpath string) Option {
+	return func(o *options) error {
+		o.userConfigDir = subpath
+		return nil
+	}
+}
+
+// WithEnvExpansion replaces each ${NAME} in the clouds’ string values with the
+// value of the environment variable NAME, or the empty string if it is unset.
+//
+// Expansion is a single pass and nested expansion is not performed: if a
+// variable’s value itself contains ${...} or $, it is inserted as-is. A bare
+// $NAME is not expanded, so values such as passwords may contain $ freely.
+func WithEnvExpansion() Option {
+	return func(o *options) error {
+		o.expandEnv = true
+		return nil
+	}
+}
+
+// WithClientCache makes Authenticate, and the helpers built on it, reuse a
+// previously authenticated ProviderClient for a cloud rather than
+// authenticating on every call. A cached client is replaced once its token is
+// within a few minutes of expiring, or if the cloud’s config or the call’s
+// ClientOptions have changed since it was built. Use InvalidateClient to
+// discard one explicitly.
+//
+// The IDs of the user and project domains are also remembered from each v3
+// token, so a cloud whose config names its domains is later authenticated
+// with their IDs instead, sparing Keystone from resolving the names again.
+// They are forgotten if the cloud’s config changes.
+//
+// Because cached clients are shared, callers must not modify them. Without
+// this option, every call returns a fresh client.
+func WithClientCache() Option {
+	return func(o *options) error {
+		o.cache = true
+		return nil
+	}
+}
+
+// WithRejectDuplicateKeys makes loading fail with a *ParseError if a key
+// appears more than once in the same mapping within the clouds section, such
+// as two auth_url lines in one auth block or two clouds with the same name.
+// The error names each offending cloud and key.
+//
+// By default, as the YAML decoder does, the last of duplicate keys wins.
+func WithRejectDuplicateKeys() Option {
+	return func(o *options) error {
+		o.noDups = true
+		return nil
+	}
+}
+
+// WithRejectUnknownKeys makes loading fail with a *ParseError if a cloud entry
+// or its auth block within the clouds section has a key that is not part of
+// the clouds.yaml schema this package parses, such as a misspelled auth_ur.
+// The error names each offending cloud and key. This suits linting configs,
+// such as in CI.
+//
+// By default, unknown keys in a cloud entry are kept for Extra and export,
+// and unknown keys in an auth block are ignored.
+func WithRejectUnknownKeys() Option {
+	return func(o *options) error {
+		o.noUnknown = true
+		return nil
+	}
+}
+
+// WithAllDisabledAsEmpty makes a document in which no cloud is enabled, as
+// when every cloud is disabled or defines no credentials, count as empty: New
+// skips such a file and goes on searching, and the From* functions return a
+// *ParseError whose Err is ErrEmptyConfig.
+//
+// By default, such a document is usable and yields a Config with no enabled
+// clouds, so a file whose clouds are all disabled on purpose still stops the
+// search.
+func WithAllDisabledAsEmpty() Option {
+	return func(o *options) error {
+		o.disabledAsEmpty = true
+		return nil
+	}
+}
+
+// WithEnvOverlay makes OS_* environment variables override the settings of
+// the selected cloud: the one named by OS_CLOUD, or otherwise chosen as
+// documented by DefaultName. Other clouds are unaffected.
+//
+// Each variable is OS_ followed by a clouds.yaml field name in upper case.
+// Every auth field may be overridden, such as OS_AUTH_URL, OS_USERNAME,
+// OS_PASSWORD, and OS_PROJECT_NAME, along with OS_AUTH_TYPE,
+// OS_REGION_NAME, OS_INTERFACE, OS_CACERT, OS_CERT, OS_KEY, and the
+// OS_<SERVICE>_API_VERSION variables. OS_TENANT_NAME and OS_TENANT_ID are
+// accepted in place of OS_PROJECT_NAME and OS_PROJECT_ID. Variables that are
+// unset or empty are ignored. WithEnvPrefix replaces the OS_ prefix.
+func WithEnvOverlay() Option {
+	return func(o *options) error {
+		o.envOverlay = true
+		return nil
+	}
+}
+
+// WithEnvPrefix sets the prefix of the environment variables WithEnvOverlay
+// reads in place of OS_, so tools that namespace their variables don’t
+// collide with others in the same environment. Each variable is the prefix
+// followed by a clouds.yaml field name in upper case: with the prefix
+// MYTOOL_OS_, the password comes from MYTOOL_OS_PASSWORD and the region from
+// MYTOOL_OS_REGION_NAME. The cloud the overlay applies to is still chosen as
+// documented by DefaultName, from OS_CLOUD and the like.
+func WithEnvPrefix(prefix string) Option {
+	return func(o *options) error {
+		o.envPrefix = prefix
+		return nil
+	}
+}
+
+// WithSecretDir reads secrets from files in dir, as when a Kubernetes secret
+// is mounted as a directory, so they can be kept out of clouds.yaml entirely.
+// A file named <cloud>.<field>, such as prod.password, sets that secret field
+// of that cloud’s auth block; the supported fields are password, token, and
+// application_credential_secret. A trailing newline is ignored, and clouds or
+// fields with no file are left as they are.
+func WithSecretDir(dir string) Option {
+	return func(o *options) error {
+		o.secretDir = dir
+		return nil
+	}
+}
+
+// WithPasswordCommandTimeout sets how long a cloud’s password_command may run
+// before it is killed, along with any processes it started, and loading fails.
+// The default is five seconds, enough for a password manager but short enough
+// that a helper stuck waiting on a prompt does not stall the program. The
+// timeout must be positive.
+func WithPasswordCommandTimeout(d time.Duration) Option {
+	return func(o *options) error {
+		if d <= 0 {
+			return errors.New("config: password_command timeout must be positive")
+		}
+		o.commandTimeout = d
+		return nil
+	}
+}
+
+// defaultMaxSize is the default limit on the size of content read from a file
+// or reader.
+const defaultMaxSize = 4 << 20
+
+// WithMaxSize sets the largest clouds.yaml content, in bytes, that FromFile,
+// FromReader, and the other functions reading a file or reader will accept;
+// for compressed content, the limit applies once decompressed. Larger content
+// is rejected with a *ParseError without being read in full, which guards
+// against pointing at a huge file or stream by mistake. The default is 4 MiB.
+// The limit must be positive.
+func WithMaxSize(n int64) Option {
+	return func(o *options) error {
+		if n <= 0 {
+			return errors.New("config: maximum size must be positive")
+		}
+		o.maxSize = n
+		return nil
+	}
+}
+
+// WithTokenFileReload re-reads each cloud’s token_file whenever the cloud is
+// looked up, as by Get or Authenticate, rather than only when the file is
+// loaded, so a token rotated out of band is picked up without reloading.
+// Results covering every cloud, such as those of GetAll, still reflect the
+// tokens read at load time.
+func WithTokenFileReload() Option {
+	return func(o *options) error {
+		o.reloadTokens = true
+		return nil
+	}
+}
+
+// WithStrictDiscovery makes New fail, rather than use the first file found, if
+// more than one of the locations it searches holds a usable clouds.yaml file.
+// Several such files usually mean a stale copy is shadowing the intended one,
+// so this helps enforce a single source of truth. Locations naming the same
+// file are counted once.
+func WithStrictDiscovery() Option {
+	return func(o *options) error {
+		o.strictDiscovery = true
+		return nil
+	}
+}
+
+// WithPublicCloudsPath sets the clouds-public.yaml file that defines the
+// vendor profiles cloud entries name with profile, such as:
+//
+//	clouds:
+//	  prod:
+//	    profile: examplecloud
+//	    auth:
+//	      username: admin
+//
+// A profile’s settings, under public-clouds in that file, apply to each cloud
+// naming it unless the cloud entry sets them itself. By default,
+// clouds-public.yaml is looked for in the directories searched for
+// clouds.yaml, except the one named by $OS_CLIENT_CONFIG_FILE. It is only read
+// if some cloud names a profile, and loading fails if the file cannot be read
+// or the profile is not defined.
+func WithPublicCloudsPath(path string) Option {
+	return func(o *options) error {
+		o.publicCloudsPath = path
+		return nil
+	}
+}
+
+// WithPublicCloudsURL is like WithPublicCloudsPath, but fetches
+// clouds-public.yaml over HTTP or HTTPS, for organizations that serve their
+// own curated profiles. It takes precedence over WithPublicCloudsPath.
+func WithPublicCloudsURL(url string) Option {
+	return func(o *options) error {
+		o.publicCloudsURL = url
+		return nil
+	}
+}
+
+// WithSecretPrompt sets a function to ask the user for a secret, such as by
+// reading a password from the terminal, as a last resort. When a method that
+// hands out or checks credentials, such as Get, GetUnscoped, Authenticate,
+// Validate, or VerifyAll, needs a secret that the cloud’s auth type requires,
+// such as the password, and no source supplied it, not even a provider set by
+// WithCredentialProviders, the function is called with the cloud’s name and
+// the field, one of password, token, passcode, or
+// application_credential_secret.
+//
+// The secret is used for that call only and never stored, so the function is
+// called again the next time it is needed, and it never appears in errors or
+// exports. An error from the function fails the call.
+func WithSecretPrompt(prompt func(cloud, field string) (string, error)) Option {
+	return func(o *options) error {
+		o.prompt = prompt
+		return nil
+	}
+}
+
+// WithFormat forces content to be parsed as the given format instead of
+// detecting it, as FormatAuto does by default (see Format). This removes any
+// ambiguity for content with no file name to go by, such as standard input,
+// and makes a document in the wrong format fail with an error from the
+// expected parser. It applies to secure.yaml content as well.
+func WithFormat(f Format) Option {
+	return func(o *options) error {
+		if f < FormatAuto || f > FormatTOML {
+			return errors.New("config: unknown format " + f.String())
+		}
+		o.format = f
+		return nil
+	}
+}
+
+// WithHomeDirFunc sets the function that returns the user’s home directory,
+// under which the user’s config directory (see WithUserConfigDir) is
+// searched. By default, the home directory is taken from $HOME or else the
+// user’s passwd entry, which some environments, such as static binaries
+// built without cgo for sandboxes, cannot provide. If f returns an error or
+// the empty string, the user’s config directory is not searched. A nil f
+// restores the default.
+func WithHomeDirFunc(f func() (string, error)) Option {
+	return func(o *options) error {
+		o.homeDirFunc = f
+		return nil
+	}
+}
+
+// WithWatchInterval sets how often Watch checks its file for changes. The
+// default is two seconds. The interval must be positive.
+func WithWatchInterval(d time.Duration) Option {
+	return func(o *options) error {
+		if d <= 0 {
+			return errors.New("config: watch interval must be positive")
+		}
+		o.watchInterval = d
+		return nil
+	}
+}
+
+// WithMinIdentityVersion makes loading fail with a *ParseError if any enabled
+// cloud would authenticate with an identity API version below version, which
+// must be "2" or "3". The error names each offending cloud. This enforces a
+// policy such as forbidding v2 auth entirely.
+//
+// A cloud’s version is taken from its identity_api_version, its auth_type,
+// such as v2password, the version in its auth_url, or fields only v3
+// supports, such as a domain, in that order. A cloud that gives none of
+// these is allowed, as the version is then negotiated with the server. By
+// default, both versions are allowed.
+func WithMinIdentityVersion(version string) Option {
+	return func(o *options) error {
+		if version != "2" && version != "3" {
+			return errors.New("config: unsupported identity API version `" + version + "`")
+		}
+		o.minIdentity = version
+		return nil
+	}
+}
+
+// WithRegionAliases maps symbolic region names, such as home or dr, to the
+// regions they stand for, so that a cloud whose region_name or regions list
+// uses one gets the concrete region instead. This lets teams refer to regions
+// by role and survive renames in one place. A cloud entry, or the profile it
+// names in clouds-public.yaml, may also map names itself, with its
+// region_aliases field, which takes precedence over m. Names neither maps are
+// left as they are.
+func WithRegionAliases(m map[string]string) Option {
+	return func(o *options) error {
+		o.regionAliases = make(map[string]string, len(m))
+		for k, v := range m {
+			o.regionAliases[k] = v
+		}
+		return nil
+	}
+}
+
+// WithSecretHeaders marks the named HTTP headers, set by clouds’ headers
+// fields, as holding secrets, so that redacted output such as
+// ExportCloudRedacted masks their values. Headers whose names suggest a
+// secret, such as X-Api-Key or X-Auth-Token, are masked regardless. Names
+// are matched without regard to case.
+func WithSecretHeaders(names ...string) Option {
+	return func(o *options) error {
+		if o.secretHeaders == nil {
+			o.secretHeaders = map[string]bool{}
+		}
+		for _, name := range names {
+			o.secretHeaders[http.CanonicalHeaderKey(name)] = true
+		}
+		return nil
+	}
+}
+
+// WithDefaultAuthType sets the auth type of cloud entries that omit
+// auth_type, such as v3applicationcredential for organizations that never use
+// passwords; such entries are treated as if they set it. Without this option
+// the default is password. The auth type must be one RequiredFields supports.
+func WithDefaultAuthType(t string) Option {
+	return func(o *options) error {
+		if _, ok := requiredFields[t]; !ok {
+			return errors.New("config: unsupported default auth_type `" + t + "`")
+		}
+		o.defaultAuthType = t
+		return nil
+	}
+}
+
+// WithTransform applies f to every cloud’s options as the Config is built,
+// replacing them with its result. This centralizes uniform rewrites, such as
+// injecting a common domain or mapping internal hostnames to reachable ones.
+//
+// Transforms run last, after every other step of loading, so f sees each
+// cloud’s final options. Multiple transforms run in the order given.
+func WithTransform(f func(name string, opts gophercloud.AuthOptions) gophercloud.AuthOptions) Option {
+	return func(o *options) error {
+		o.transforms = append(o.transforms, f)
+		return nil
+	}
+}
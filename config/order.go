@@ -0,0 +1,83 @@
+package config
+
+import (
+	"fmt"
+	"gopkg.in/yaml.v2"
+	"sync"
+)
+
+// OrderedNames satisfies the Config interface.
+func (c *configImpl) OrderedNames() []string {
+	names := c.Names()
+	enabled := make(map[string]bool, len(names))
+	for _, k := range names {
+		enabled[k] = true
+	}
+	c.mu.RLock()
+	order := c.order
+	c.mu.RUnlock()
+	var ordered []string
+	for _, k := range order.names() {
+		if enabled[k] {
+			ordered = append(ordered, k)
+			delete(enabled, k)
+		}
+	}
+	for _, k := range names {
+		if enabled[k] {
+			ordered = append(ordered, k)
+		}
+	}
+	return ordered
+}
+
+// cloudOrder records the documents a Config was parsed from, to work out the
+// order they define clouds in only when OrderedNames first asks for it, so
+// loading pays nothing for it. A cloudOrder is shared by the Configs that
+// Filter and Only derive from the one it was made for.
+type cloudOrder struct {
+	once      sync.Once
+	cloudsKey string
+	docs      [][]byte
+	order     []string
+}
+
+// names returns the names of the clouds the documents define, in order. A
+// nil cloudOrder, for a Config not parsed from documents, has none.
+func (o *cloudOrder) names() []string {
+	if o == nil {
+		return nil
+	}
+	o.once.Do(func() {
+		o.order = definitionOrder(o.cloudsKey, o.docs...)
+		o.docs = nil
+	})
+	return o.order
+}
+
+// definitionOrder returns the names of the clouds defined under cloudsKey in
+// each document, in the order the documents define them and without
+// repeats. A document that cannot be decoded contributes no names.
+func definitionOrder(cloudsKey string, docs ...[]byte) []string {
+	var order []string
+	seen := map[string]bool{}
+	for _, b := range docs {
+		var doc yaml.MapSlice
+		if err := yaml.Unmarshal(b, &doc); err != nil {
+			continue
+		}
+		for _, item := range doc {
+			if fmt.Sprint(item.Key) != cloudsKey {
+				continue
+			}
+			clouds, _ := item.Value.(yaml.MapSlice)
+			for _, e := range clouds {
+				if k := fmt.Sprint(e.Key); !seen[k] {
+					seen[k] = true
+					order = append(order, k)
+				}
+			}
+		}
+	}
+	return order
+}
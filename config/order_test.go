@@ -0,0 +1,66 @@
+package config
+
+import (
+	"github.com/gophercloud/gophercloud"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const orderTestClouds = `
+clouds:
+  zulu:
+    auth: {auth_url: http://z}
+  alpha:
+    auth: {auth_url: http://a}
+  off:
+    disabled: true
+    auth: {auth_url: http://o}
+  mike:
+    auth: {auth_url: http://m}
+`
+
+func TestOrderedNames(t *testing.T) {
+	c, err := FromBytes([]byte(orderTestClouds))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := c.OrderedNames(), []string{"zulu", "alpha", "mike"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("OrderedNames() = %v, want %v", got, want)
+	}
+	if got, want := c.Names(), []string{"alpha", "mike", "zulu"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Names() = %v, want %v", got, want)
+	}
+
+	// Clouds no file defined come last, sorted.
+	c.Merge(FromMap(map[string]gophercloud.AuthOptions{
+		"golf":  {IdentityEndpoint: "http://g"},
+		"bravo": {IdentityEndpoint: "http://b"},
+	}))
+	if got, want := c.OrderedNames(), []string{"zulu", "alpha", "mike", "bravo", "golf"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("OrderedNames() after Merge = %v, want %v", got, want)
+	}
+
+	f := c.Filter(func(name string, _ gophercloud.AuthOptions) bool { return name != "alpha" })
+	if got, want := f.OrderedNames(), []string{"zulu", "mike", "bravo", "golf"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Filter(...).OrderedNames() = %v, want %v", got, want)
+	}
+}
+
+func TestOrderedNamesSecure(t *testing.T) {
+	secure := "clouds:\n  yankee:\n    auth: {auth_url: http://y}\n  mike:\n    auth: {password: p}\n"
+	c, err := FromReaders(strings.NewReader(orderTestClouds), strings.NewReader(secure))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := c.OrderedNames(), []string{"zulu", "alpha", "mike", "yankee"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("OrderedNames() = %v, want %v", got, want)
+	}
+}
+
+func TestOrderedNamesFromMap(t *testing.T) {
+	c := FromMap(map[string]gophercloud.AuthOptions{"b": {}, "a": {}})
+	if got, want := c.OrderedNames(), []string{"a", "b"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("OrderedNames() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,99 @@
+package config
+
+import (
+	"errors"
+	"github.com/gophercloud/gophercloud"
+	"sort"
+)
+
+// overrideAliases maps auth field names that GetWithOverrides accepts to the
+// fields they set, where authOptions would otherwise prefer another field.
+var overrideAliases = map[string]string{
+	"tenant_name": "project_name",
+	"tenant_id":   "project_id",
+	"domain_name": "user_domain_name",
+	"domain_id":   "user_domain_id",
+}
+
+// overridePairs maps each auth field that GetWithOverrides sets to the other
+// way of naming the same thing, which an override of one clears, so the file’s
+// value for the other is not sent alongside it.
+var overridePairs = map[string]string{
+	"project_id":          "project_name",
+	"project_name":        "project_id",
+	"user_domain_id":      "user_domain_name",
+	"user_domain_name":    "user_domain_id",
+	"project_domain_id":   "project_domain_name",
+	"project_domain_name": "project_domain_id",
+	"user_id":             "username",
+	"username":            "user_id",
+}
+
+// GetWithOverrides satisfies the Config interface.
+func (c *configImpl) GetWithOverrides(name string, overrides map[string]string) (gophercloud.AuthOptions, error) {
+	opts, err := c.Get(name)
+	if err != nil {
+		return gophercloud.AuthOptions{}, err
+	}
+	if region := overrides["region_name"]; region != "" {
+		v, err := c.cloud(name)
+		if err != nil {
+			return gophercloud.AuthOptions{}, err
+		}
+		if !v.hasRegion(region) {
+			return gophercloud.AuthOptions{}, errors.New("config: cloud `" + name + "` has no region `" + region + "`")
+		}
+	}
+	a := newAuthYAML(opts)
+	fs := stringFields(a)
+	keys := make([]string, 0, len(overrides))
+	for k := range overrides {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	set := map[string]bool{}
+	for _, k := range keys {
+		if k == "region_name" {
+			continue
+		}
+		field := k
+		if alias, ok := overrideAliases[k]; ok {
+			field = alias
+		}
+		p, ok := fs[field]
+		if !ok || indirections[field] {
+			return gophercloud.AuthOptions{}, errors.New("config: unknown auth field `" + k + "`")
+		}
+		if overrides[k] != "" {
+			*p = overrides[k]
+			set[field] = true
+		}
+	}
+	for field := range set {
+		if other := overridePairs[field]; other != "" && !set[other] {
+			*fs[other] = ""
+		}
+	}
+	// Only the fields clouds.yaml can set survive the round trip through an
+	// auth block, so carry the rest over.
+	result := a.authOptions()
+	result.AllowReauth = opts.AllowReauth
+	return result, nil
+}
+
+// hasRegion reports whether a region is one the cloud uses: its region_name
+// or an entry of its regions list. A cloud that names no regions accepts any.
+func (v cloud) hasRegion(region string) bool {
+	if v.region == "" && len(v.regions) == 0 {
+		return true
+	}
+	if region == v.region {
+		return true
+	}
+	for _, r := range v.regions {
+		if r == region {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,129 @@
+package config
+
+import (
+	"testing"
+)
+
+const overridesTestClouds = `
+clouds:
+  a:
+    region_name: r1
+    regions: [r1, r2]
+    auth:
+      auth_url: http://a/v3
+      username: u
+      password: p
+      project_name: a
+      user_domain_name: d
+`
+
+func TestGetWithOverrides(t *testing.T) {
+	c, err := FromBytes([]byte(overridesTestClouds))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		name      string
+		overrides map[string]string
+		check     func(t *testing.T, got map[string]string)
+	}{
+		{
+			name:      "project_id clears project_name",
+			overrides: map[string]string{"project_id": "123"},
+			check: func(t *testing.T, got map[string]string) {
+				if got["TenantID"] != "123" || got["TenantName"] != "" {
+					t.Errorf("TenantID, TenantName = %q, %q, want 123 and empty", got["TenantID"], got["TenantName"])
+				}
+			},
+		},
+		{
+			name:      "tenant_id alias clears project_name",
+			overrides: map[string]string{"tenant_id": "123"},
+			check: func(t *testing.T, got map[string]string) {
+				if got["TenantID"] != "123" || got["TenantName"] != "" {
+					t.Errorf("TenantID, TenantName = %q, %q, want 123 and empty", got["TenantID"], got["TenantName"])
+				}
+			},
+		},
+		{
+			name:      "both of a pair are kept",
+			overrides: map[string]string{"project_id": "123", "project_name": "b"},
+			check: func(t *testing.T, got map[string]string) {
+				if got["TenantID"] != "123" || got["TenantName"] != "b" {
+					t.Errorf("TenantID, TenantName = %q, %q, want 123 and b", got["TenantID"], got["TenantName"])
+				}
+			},
+		},
+		{
+			name:      "user_domain_id clears user_domain_name",
+			overrides: map[string]string{"user_domain_id": "did"},
+			check: func(t *testing.T, got map[string]string) {
+				if got["DomainID"] != "did" || got["DomainName"] != "" {
+					t.Errorf("DomainID, DomainName = %q, %q, want did and empty", got["DomainID"], got["DomainName"])
+				}
+			},
+		},
+		{
+			name:      "user_id clears username",
+			overrides: map[string]string{"user_id": "uid"},
+			check: func(t *testing.T, got map[string]string) {
+				if got["UserID"] != "uid" || got["Username"] != "" {
+					t.Errorf("UserID, Username = %q, %q, want uid and empty", got["UserID"], got["Username"])
+				}
+			},
+		},
+		{
+			name:      "region_name is accepted",
+			overrides: map[string]string{"region_name": "r2"},
+			check: func(t *testing.T, got map[string]string) {
+				if got["TenantName"] != "a" {
+					t.Errorf("TenantName = %q, want a", got["TenantName"])
+				}
+			},
+		},
+		{
+			name:      "empty values are ignored",
+			overrides: map[string]string{"project_id": ""},
+			check: func(t *testing.T, got map[string]string) {
+				if got["TenantName"] != "a" {
+					t.Errorf("TenantName = %q, want a", got["TenantName"])
+				}
+			},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			opts, err := c.GetWithOverrides("a", tt.overrides)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tt.check(t, map[string]string{
+				"TenantID":   opts.TenantID,
+				"TenantName": opts.TenantName,
+				"DomainID":   opts.DomainID,
+				"DomainName": opts.DomainName,
+				"UserID":     opts.UserID,
+				"Username":   opts.Username,
+			})
+		})
+	}
+	if opts, _ := c.Get("a"); opts.TenantName != "a" || opts.TenantID != "" {
+		t.Errorf("stored config changed: %+v", opts)
+	}
+}
+
+func TestGetWithOverridesErrors(t *testing.T) {
+	c, err := FromBytes([]byte(overridesTestClouds))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for overrides, want := range map[string]string{
+		"bogus":        "config: unknown auth field `bogus`",
+		"password_env": "config: unknown auth field `password_env`",
+		"region_name":  "config: cloud `a` has no region `r9`",
+	} {
+		_, err := c.GetWithOverrides("a", map[string]string{overrides: "r9"})
+		if err == nil || err.Error() != want {
+			t.Errorf("GetWithOverrides(%s) = %v, want %s", overrides, err, want)
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// precedenceLayers lists the layers that can set a password, from lowest to
+// highest precedence, as documented for the package.
+var precedenceLayers = []Layer{
+	LayerProfile,
+	LayerFile,
+	LayerSecure,
+	LayerSecretDir,
+	LayerIndirection,
+	LayerEnv,
+}
+
+// loadPrecedence loads cloud a with a password set by each of the given
+// layers, the value naming the layer, and returns the password in effect and
+// the layer EffectiveCloud reports for it.
+func loadPrecedence(t *testing.T, layers map[Layer]bool, indirection string) (string, Layer) {
+	t.Helper()
+	dir := t.TempDir()
+	t.Setenv("OS_CLOUD", "a")
+	t.Setenv("PRECEDENCE_TEST_PASSWORD", "")
+	t.Setenv("PRECEDENCE_TEST_PW", "")
+
+	var entry strings.Builder
+	entry.WriteString("clouds:\n  a:\n")
+	opts := []Option{WithEnvOverlay(), WithEnvPrefix("PRECEDENCE_TEST_")}
+	if layers[LayerProfile] {
+		profiles := filepath.Join(dir, "clouds-public.yaml")
+		writeFile(t, profiles, "public-clouds:\n  vendor:\n    auth:\n      password: profile\n")
+		opts = append(opts, WithPublicCloudsPath(profiles))
+		entry.WriteString("    profile: vendor\n")
+	}
+	entry.WriteString("    auth:\n      auth_url: http://a/v3\n      username: u\n")
+	if layers[LayerFile] {
+		entry.WriteString("      password: file\n")
+	}
+	if layers[LayerIndirection] {
+		switch indirection {
+		case "password_env":
+			t.Setenv("PRECEDENCE_TEST_PW", "indirection")
+			entry.WriteString("      password_env: PRECEDENCE_TEST_PW\n")
+		case "password_command":
+			entry.WriteString("      password_command: echo indirection\n")
+		}
+	}
+	var secure io.Reader
+	if layers[LayerSecure] {
+		secure = strings.NewReader("clouds:\n  a:\n    auth:\n      password: secure\n")
+	}
+	if layers[LayerSecretDir] {
+		secrets := filepath.Join(dir, "secrets")
+		if err := os.Mkdir(secrets, 0700); err != nil {
+			t.Fatal(err)
+		}
+		writeFile(t, filepath.Join(secrets, "a.password"), "secret_dir\n")
+		opts = append(opts, WithSecretDir(secrets))
+	}
+	if layers[LayerEnv] {
+		t.Setenv("PRECEDENCE_TEST_PASSWORD", "env")
+	}
+
+	c, err := FromReaders(bytes.NewReader([]byte(entry.String())), secure, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, sources, err := c.EffectiveCloud("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range sources {
+		if s.Field == "auth.password" {
+			return got.Password, s.Layer
+		}
+	}
+	return got.Password, ""
+}
+
+func TestPrecedence(t *testing.T) {
+	// Every combination of layers, each of which sets the password: the
+	// highest layer present must win.
+	for mask := 0; mask < 1<<len(precedenceLayers); mask++ {
+		layers := map[Layer]bool{}
+		var want Layer
+		var names []string
+		for i, l := range precedenceLayers {
+			if mask&(1<<i) != 0 {
+				layers[l] = true
+				want = l
+				names = append(names, string(l))
+			}
+		}
+		t.Run(fmt.Sprintf("%02d_%s", mask, strings.Join(names, "+")), func(t *testing.T) {
+			password, layer := loadPrecedence(t, layers, "password_env")
+			if password != string(want) || layer != want {
+				t.Errorf("password = %q from layer %q, want %q from layer %q", password, layer, want, want)
+			}
+		})
+	}
+}
+
+func TestPrecedenceIndirections(t *testing.T) {
+	// Each kind of indirection ranks the same: above secret files, below
+	// the environment.
+	for _, indirection := range []string{"password_env", "password_command"} {
+		for _, tt := range []struct {
+			layers []Layer
+			want   Layer
+		}{
+			{[]Layer{LayerFile, LayerIndirection}, LayerIndirection},
+			{[]Layer{LayerSecure, LayerSecretDir, LayerIndirection}, LayerIndirection},
+			{[]Layer{LayerIndirection, LayerEnv}, LayerEnv},
+		} {
+			layers := map[Layer]bool{}
+			for _, l := range tt.layers {
+				layers[l] = true
+			}
+			password, layer := loadPrecedence(t, layers, indirection)
+			if password != string(tt.want) || layer != tt.want {
+				t.Errorf("%s with %v: password = %q from layer %q, want %q", indirection, tt.layers, password, layer, tt.want)
+			}
+		}
+	}
+}
+
+func TestPrecedenceFileURI(t *testing.T) {
+	// A file:// URI is read in place of the value that holds it, so it
+	// ranks with the layer that set it: here the cloud entry.
+	dir := t.TempDir()
+	pw := filepath.Join(dir, "pw")
+	writeFile(t, pw, "from-uri\n")
+	entry := "clouds:\n  a:\n    auth:\n      auth_url: http://a/v3\n      password: file://" + filepath.ToSlash(pw) + "\n"
+	for _, tt := range []struct {
+		secure string
+		want   string
+	}{
+		{"", "from-uri"},
+		{"clouds:\n  a:\n    auth:\n      password: secure\n", "secure"},
+	} {
+		var secure io.Reader
+		if tt.secure != "" {
+			secure = strings.NewReader(tt.secure)
+		}
+		c, err := FromReaders(strings.NewReader(entry), secure)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if opts, _ := c.Get("a"); opts.Password != tt.want {
+			t.Errorf("password = %q, want %q", opts.Password, tt.want)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package config
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+	"net/url"
+)
+
+// ProbeStage identifies the step of ProbeEndpoint that failed.
+type ProbeStage string
+
+// The steps of ProbeEndpoint, in the order they are taken.
+const (
+	// ProbeDNS is resolving the auth_url’s host name.
+	ProbeDNS ProbeStage = "dns"
+
+	// ProbeConnect is opening a TCP connection to the host.
+	ProbeConnect ProbeStage = "connect"
+
+	// ProbeTLS is the TLS handshake, for an https auth_url.
+	ProbeTLS ProbeStage = "tls"
+)
+
+// ProbeError is returned by ProbeEndpoint when the endpoint cannot be reached.
+type ProbeError struct {
+	// Cloud is the name of the cloud probed.
+	Cloud string
+
+	// Stage is the step that failed.
+	Stage ProbeStage
+
+	// Err is the underlying error.
+	Err error
+}
+
+func (e *ProbeError) Error() string {
+	return "config: cannot reach cloud `" + e.Cloud + "` (" + string(e.Stage) + "): " + e.Err.Error()
+}
+
+// Unwrap returns the underlying error.
+func (e *ProbeError) Unwrap() error {
+	return e.Err
+}
+
+// ProbeEndpoint satisfies the Config interface.
+func (c *configImpl) ProbeEndpoint(ctx context.Context, name string) error {
+	v, err := c.cloud(name)
+	if err != nil {
+		return err
+	}
+	u, err := url.Parse(v.auth.IdentityEndpoint)
+	if err != nil || u.Host == "" {
+		return errors.New("config: cloud `" + name + "` has an invalid auth_url")
+	}
+	host, port := u.Hostname(), u.Port()
+	if port == "" {
+		port = "80"
+		if u.Scheme == "https" {
+			port = "443"
+		}
+	}
+
+	addrs, err := net.DefaultResolver.LookupHost(ctx, host)
+	if err != nil {
+		return &ProbeError{name, ProbeDNS, err}
+	}
+	var (
+		d    net.Dialer
+		conn net.Conn
+	)
+	for _, addr := range addrs {
+		if conn, err = d.DialContext(ctx, "tcp", net.JoinHostPort(addr, port)); err == nil {
+			break
+		}
+	}
+	if err != nil {
+		return &ProbeError{name, ProbeConnect, err}
+	}
+	defer conn.Close()
+	if u.Scheme != "https" {
+		return nil
+	}
+
+	tc, err := v.tls.tlsConfig(false)
+	if err != nil {
+		return err
+	}
+	if tc == nil {
+		tc = &tls.Config{}
+	}
+	if tc.ServerName == "" {
+		tc.ServerName = host
+	}
+	if err := tls.Client(conn, tc).HandshakeContext(ctx); err != nil {
+		return &ProbeError{name, ProbeTLS, err}
+	}
+	return nil
+}
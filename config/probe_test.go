@@ -0,0 +1,57 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestProbeEndpointHandshakeHonorsCancel(t *testing.T) {
+	// The listener accepts connections but never answers the handshake.
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+	c, err := FromBytes([]byte("clouds:\n  a:\n    auth: {auth_url: 'https://" + l.Addr().String() + "/v3'}\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(200*time.Millisecond, cancel)
+	start := time.Now()
+	err = c.ProbeEndpoint(ctx, "a")
+	var probeErr *ProbeError
+	if !errors.As(err, &probeErr) || probeErr.Stage != ProbeTLS {
+		t.Errorf("ProbeEndpoint() = %v, want a tls *ProbeError", err)
+	}
+	if d := time.Since(start); d > 2*time.Second {
+		t.Errorf("ProbeEndpoint() took %v after the context was cancelled", d)
+	}
+}
+
+func TestProbeEndpointPlainHTTP(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	c, err := FromBytes([]byte("clouds:\n  a:\n    auth: {auth_url: 'http://" + l.Addr().String() + "/v3'}\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.ProbeEndpoint(context.Background(), "a"); err != nil {
+		t.Errorf("ProbeEndpoint() = %v, want nil", err)
+	}
+}
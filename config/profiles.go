@@ -0,0 +1,106 @@
+package config
+
+import (
+	"errors"
+	"gopkg.in/yaml.v2"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// publicCloudsFile is the name of the file defining the vendor profiles that
+// cloud entries may name with profile.
+const publicCloudsFile = "clouds-public.yaml"
+
+// publicCloudsTimeout bounds fetching clouds-public.yaml from a URL.
+const publicCloudsTimeout = 30 * time.Second
+
+// publicCloudPaths returns the paths searched for clouds-public.yaml by
+// default: the directories searched for clouds.yaml, in the same order.
+func publicCloudPaths(o *options) []string {
+	paths := []string{filepath.Join("./", publicCloudsFile)}
+	if d := os.Getenv("OS_CONFIG_DIR"); d != "" {
+		paths = append(paths, filepath.Join(d, publicCloudsFile))
+	}
+	if d := o.homeDir(); d != "" {
+		paths = append(paths, filepath.Join(d, o.userConfigDir, publicCloudsFile))
+	}
+	return append(paths, filepath.Join("/etc/openstack", publicCloudsFile))
+}
+
+// loadProfiles returns the profiles defined in clouds-public.yaml, keyed by
+// name, from the source set by WithPublicCloudsPath or WithPublicCloudsURL or
+// else the first of the default paths that exists. If no file is found, this
+// returns no profiles.
+func loadProfiles(o *options) (map[string]*cloudYAML, error) {
+	var (
+		b   []byte
+		src string
+		err error
+	)
+	switch {
+	case o.publicCloudsURL != "":
+		src = o.publicCloudsURL
+		b, err = fetchProfiles(src, o)
+	case o.publicCloudsPath != "":
+		src = o.publicCloudsPath
+		b, err = readProfiles(src, o)
+	default:
+		for _, p := range publicCloudPaths(o) {
+			if _, statErr := os.Stat(p); statErr == nil {
+				src = p
+				b, err = readProfiles(p, o)
+				break
+			}
+		}
+		if src == "" {
+			return nil, nil
+		}
+	}
+	if err != nil {
+		return nil, errors.New("config: cannot load profiles from " + src + ": " + err.Error())
+	}
+	var doc struct {
+		PublicClouds map[string]*cloudYAML `yaml:"public-clouds"`
+	}
+	if err := yaml.Unmarshal(b, &doc); err != nil {
+		return nil, errors.New("config: cannot parse profiles in " + src + ": " + err.Error())
+	}
+	return doc.PublicClouds, nil
+}
+
+// readProfiles reads clouds-public.yaml from a file.
+func readProfiles(path string, o *options) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return readAll(f, path, o)
+}
+
+// fetchProfiles reads clouds-public.yaml from an HTTP or HTTPS URL.
+func fetchProfiles(url string, o *options) ([]byte, error) {
+	client := &http.Client{Timeout: publicCloudsTimeout}
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.New("unexpected status " + resp.Status)
+	}
+	return readAll(resp.Body, url, o)
+}
+
+// copy returns a copy of a cloud entry that can be modified without affecting
+// the original.
+func (v *cloudYAML) copy() *cloudYAML {
+	c := *v
+	if v.Auth != nil {
+		a := *v.Auth
+		c.Auth = &a
+	}
+	return &c
+}
@@ -0,0 +1,65 @@
+package config
+
+import (
+	"errors"
+	"strings"
+)
+
+// promptedFields lists the secret fields WithSecretPrompt may ask for.
+var promptedFields = map[string]bool{
+	"password":                      true,
+	"token":                         true,
+	"passcode":                      true,
+	"application_credential_secret": true,
+}
+
+// cloudWithSecrets returns the named cloud, as cloud does, with the fallback
+// for missing secrets applied by withPrompted. Every method that hands out
+// credentials or checks them goes through this, so they all see the same
+// secrets.
+func (c *configImpl) cloudWithSecrets(name string) (cloud, error) {
+	v, err := c.cloud(name)
+	if err != nil {
+		return cloud{}, err
+	}
+	return c.withPrompted(name, v)
+}
+
+// withPrompted returns the cloud with each secret its auth type requires but
+// no source supplied obtained from the credential providers set with
+// WithCredentialProviders, or else from the prompt, if one was set with
+// WithSecretPrompt. Such secrets are not stored in the Config.
+func (c *configImpl) withPrompted(name string, v cloud) (cloud, error) {
+	if c.prompt == nil && len(c.providers) == 0 {
+		return v, nil
+	}
+	v.auth = cloneAuthOptions(v.auth)
+	secrets := map[string]*string{
+		"password":                      &v.auth.Password,
+		"token":                         &v.auth.TokenID,
+		"passcode":                      &v.auth.Passcode,
+		"application_credential_secret": &v.auth.ApplicationCredentialSecret,
+	}
+	for _, r := range requiredFields[v.effectiveAuthType()] {
+		if strings.Contains(r, "|") || !promptedFields[r] || *secrets[r] != "" {
+			continue
+		}
+		secret, ok, err := provideCredential(c.providers, name, r)
+		if err != nil {
+			return cloud{}, errors.New("config: cloud `" + name + "`: cannot obtain " + r + ": " + err.Error())
+		}
+		if ok {
+			*secrets[r] = secret
+			continue
+		}
+		if c.prompt == nil {
+			continue
+		}
+		secret, err = c.prompt(name, r)
+		if err != nil {
+			return cloud{}, errors.New("config: cloud `" + name + "`: cannot prompt for " + r + ": " + err.Error())
+		}
+		*secrets[r] = secret
+	}
+	return v, nil
+}
@@ -0,0 +1,90 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+const promptTestClouds = `
+clouds:
+  a:
+    auth:
+      auth_url: http://127.0.0.1:1/v3
+      username: u
+      project_name: p
+      user_domain_name: d
+`
+
+func TestSecretPromptAppliesToEveryCredentialPath(t *testing.T) {
+	calls := 0
+	c, err := FromBytes([]byte(promptTestClouds), WithSecretPrompt(func(cloud, field string) (string, error) {
+		calls++
+		if cloud != "a" || field != "password" {
+			t.Errorf("prompt(%q, %q), want (a, password)", cloud, field)
+		}
+		return "prompted", nil
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		name string
+		get  func() (string, error)
+	}{
+		{"Get", func() (string, error) {
+			opts, err := c.Get("a")
+			return opts.Password, err
+		}},
+		{"GetUnscoped", func() (string, error) {
+			opts, err := c.GetUnscoped("a")
+			return opts.Password, err
+		}},
+		{"GetV3Scoped", func() (string, error) {
+			opts, err := c.GetV3Scoped("a", "other", "")
+			return opts.Password, err
+		}},
+		{"ClientConfig", func() (string, error) {
+			opts, _, err := c.ClientConfig("a")
+			return opts.Password, err
+		}},
+	} {
+		password, err := tt.get()
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+		} else if password != "prompted" {
+			t.Errorf("%s: password = %q, want prompted", tt.name, password)
+		}
+	}
+	if err := c.Validate("a"); err != nil {
+		t.Errorf("Validate: %v", err)
+	}
+	if err := c.ValidateWith("a", []string{"password"}); err != nil {
+		t.Errorf("ValidateWith: %v", err)
+	}
+	if m := c.Invalid(); m != nil {
+		t.Errorf("Invalid() = %v, want nil", m)
+	}
+	if calls != 7 {
+		t.Errorf("prompt called %d times, want 7", calls)
+	}
+	if opts := c.AllIncludingDisabled()["a"]; opts.Password != "" {
+		t.Errorf("prompted password stored in Config: %q", opts.Password)
+	}
+}
+
+func TestSecretPromptErrorFailsVerify(t *testing.T) {
+	c, err := FromBytes([]byte(promptTestClouds), WithSecretPrompt(func(cloud, field string) (string, error) {
+		return "", errors.New("no terminal")
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = c.VerifyAll(context.Background())["a"]
+	if err == nil || err.Error() != "config: cloud `a`: cannot prompt for password: no terminal" {
+		t.Errorf("VerifyAll()[a] = %v, want the prompt error", err)
+	}
+	if err := c.Validate("a"); err == nil {
+		t.Error("Validate() = nil, want the prompt error")
+	}
+}
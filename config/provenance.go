@@ -0,0 +1,148 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"github.com/gophercloud/gophercloud"
+	"gopkg.in/yaml.v2"
+	"sort"
+	"strings"
+)
+
+// Layer identifies a source of cloud settings, in terms of the precedence
+// documented for the package.
+type Layer string
+
+// The layers that can set a cloud’s fields.
+const (
+	// LayerProfile is a value from the profile in clouds-public.yaml named
+	// by the cloud entry.
+	LayerProfile Layer = "profile"
+
+	// LayerFile is a value in the cloud entry itself.
+	LayerFile Layer = "file"
+
+	// LayerSecure is a value in the cloud’s secure.yaml entry.
+	LayerSecure Layer = "secure"
+
+	// LayerSecretDir is a secret file read through WithSecretDir.
+	LayerSecretDir Layer = "secret_dir"
+
+	// LayerIndirection is an indirection in the cloud entry, such as
+	// password_env.
+	LayerIndirection Layer = "indirection"
+
+	// LayerEnv is an OS_* environment variable read through
+	// WithEnvOverlay.
+	LayerEnv Layer = "env"
+
+	// LayerTransform is a function applied through WithTransform.
+	LayerTransform Layer = "transform"
+)
+
+// Source records which layer last set a field of a cloud.
+type Source struct {
+	// Field is the field’s name in clouds.yaml. Fields of the auth block
+	// are prefixed with "auth.", as in auth.password.
+	Field string
+
+	// Layer is the layer whose value is in effect.
+	Layer Layer
+}
+
+// EffectiveCloud satisfies the Config interface.
+func (c *configImpl) EffectiveCloud(name string) (gophercloud.AuthOptions, []Source, error) {
+	v, err := c.cloud(name)
+	if err != nil {
+		return gophercloud.AuthOptions{}, nil, err
+	}
+	var sources []Source
+	for field, layer := range v.sources {
+		sources = append(sources, Source{Field: field, Layer: layer})
+	}
+	sort.Slice(sources, func(i, j int) bool { return sources[i].Field < sources[j].Field })
+	return cloneAuthOptions(v.auth), sources, nil
+}
+
+// SetFields satisfies the Config interface.
+func (c *configImpl) SetFields(name string) ([]string, error) {
+	v, err := c.cloud(name)
+	if err != nil {
+		return nil, err
+	}
+	// The fields are read from the entry as ExportCloud would write it, so
+	// every kind of field counts, whichever way the cloud was built.
+	b, err := yaml.Marshal(exportEntry(v))
+	if err != nil {
+		return nil, errors.New("config: " + err.Error())
+	}
+	var doc yaml.MapSlice
+	if err := yaml.Unmarshal(b, &doc); err != nil {
+		return nil, errors.New("config: " + err.Error())
+	}
+	var fields []string
+	for _, item := range doc {
+		k := fmt.Sprint(item.Key)
+		if k != "auth" {
+			fields = append(fields, k)
+			continue
+		}
+		auth, _ := item.Value.(yaml.MapSlice)
+		for _, a := range auth {
+			fields = append(fields, "auth."+fmt.Sprint(a.Key))
+		}
+	}
+	sort.Strings(fields)
+	return fields, nil
+}
+
+// provenance tracks the layer that last set each field of a cloud entry as
+// the layers are applied in turn.
+type provenance map[string]Layer
+
+// snapshot is the state of a cloud entry’s fields before a layer is applied.
+type snapshot struct {
+	cloud, auth map[string]string
+}
+
+// take returns the current values of the fields of a cloud entry and its
+// auth block.
+func take(v *cloudYAML, a *authYAML) snapshot {
+	return snapshot{cloud: values(v), auth: values(a)}
+}
+
+// values returns the string fields of the struct v points to, keyed by their
+// names in clouds.yaml. A cloud entry’s interface list is included, with its
+// names separated by commas.
+func values(v interface{}) map[string]string {
+	m := map[string]string{}
+	for field, p := range stringFields(v) {
+		m[field] = *p
+	}
+	if c, ok := v.(*cloudYAML); ok {
+		m["interface"] = strings.Join(c.Interface, ",")
+	}
+	return m
+}
+
+// record attributes to layer every field whose value differs from before.
+// Fields the layer cleared are forgotten.
+func (p provenance) record(before snapshot, v *cloudYAML, a *authYAML, layer Layer) {
+	p.diff("", before.cloud, values(v), layer)
+	p.diff("auth.", before.auth, values(a), layer)
+}
+
+// diff attributes to layer the fields whose values differ between before and
+// after, with their names prefixed.
+func (p provenance) diff(prefix string, before, after map[string]string, layer Layer) {
+	for field, value := range after {
+		if value == before[field] {
+			continue
+		}
+		if value == "" {
+			delete(p, prefix+field)
+		} else {
+			p[prefix+field] = layer
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package config
+
+import (
+	"github.com/gophercloud/gophercloud"
+	"reflect"
+	"testing"
+)
+
+func TestSetFields(t *testing.T) {
+	conf, err := FromBytes([]byte(`
+clouds:
+  a:
+    region_name: RegionOne
+    regions: [RegionOne, RegionTwo]
+    interface: internal
+    insecure: true
+    connect_retries: 2
+    identity_api_version: 3
+    headers: {X-Tenant-Hint: blue}
+    vendor_block: {tuning: fast}
+    auth:
+      auth_url: http://a/v3
+      username: u
+      password: p
+      user_domain_name: Default
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := conf.SetFields("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"auth.auth_url",
+		"auth.password",
+		"auth.user_domain_name",
+		"auth.username",
+		"connect_retries",
+		"headers",
+		"identity_api_version",
+		"interface",
+		"region_name",
+		"regions",
+		"vendor_block",
+		"verify",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("SetFields(a) = %q, want %q", got, want)
+	}
+	if _, err := conf.SetFields("missing"); err == nil || err.Error() != "config: cloud `missing` not found" {
+		t.Errorf("SetFields(missing) error = %v", err)
+	}
+}
+
+func TestSetFieldsWithoutDocument(t *testing.T) {
+	conf := FromMap(map[string]gophercloud.AuthOptions{
+		"a": {IdentityEndpoint: "http://a/v3", Username: "u"},
+	})
+	if err := conf.Add("b", gophercloud.AuthOptions{IdentityEndpoint: "http://b/v3", TokenID: "t"}); err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string][]string{
+		"a": {"auth.auth_url", "auth.username"},
+		"b": {"auth.auth_url", "auth.token"},
+	} {
+		got, err := conf.SetFields(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("SetFields(%s) = %q, want %q", name, got, want)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package config
+
+import (
+	"fmt"
+	"gopkg.in/yaml.v2"
+)
+
+// Raw satisfies the Config interface.
+func (c *configImpl) Raw() map[string]interface{} {
+	// The document is decoded afresh from its source on each call, which
+	// keeps loading cheap for the majority of callers who never ask for it
+	// and guarantees the result shares nothing with the Config.
+	c.mu.RLock()
+	src := c.src
+	c.mu.RUnlock()
+	if src == nil {
+		return nil
+	}
+	doc := map[string]interface{}{}
+	if err := yaml.Unmarshal(src, &doc); err != nil {
+		return nil
+	}
+	return copyTree(doc).(map[string]interface{})
+}
+
+// AuthBlock satisfies the Config interface.
+func (c *configImpl) AuthBlock(name string) (map[string]interface{}, error) {
+	if _, err := c.cloud(name); err != nil {
+		return nil, err
+	}
+	clouds, _ := c.Raw()[c.cloudsKey].(map[string]interface{})
+	entry, _ := clouds[name].(map[string]interface{})
+	auth, _ := entry["auth"].(map[string]interface{})
+	return auth, nil
+}
+
+// Extra satisfies the Config interface.
+func (c *configImpl) Extra(name string) (map[string]interface{}, error) {
+	v, err := c.cloud(name)
+	if err != nil {
+		return nil, err
+	}
+	if len(v.extra) == 0 {
+		return nil, nil
+	}
+	return copyTree(v.extra).(map[string]interface{}), nil
+}
+
+// copyTree returns a deep copy of a decoded YAML tree, with every mapping
+// converted to a map[string]interface{}.
+func copyTree(v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(t))
+		for k, e := range t {
+			m[k] = copyTree(e)
+		}
+		return m
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(t))
+		for k, e := range t {
+			m[fmt.Sprint(k)] = copyTree(e)
+		}
+		return m
+	case []interface{}:
+		l := make([]interface{}, len(t))
+		for i, e := range t {
+			l[i] = copyTree(e)
+		}
+		return l
+	}
+	return v
+}
@@ -0,0 +1,128 @@
+package config
+
+import (
+	"errors"
+	"sort"
+)
+
+// regionYAML is an entry in a cloud’s regions list. OpenStack accepts either a
+// region name or a mapping with a name field, such as {name: RegionOne}, and
+// optionally values overriding the cloud’s own settings in that region. Of the
+// values, only interface is used.
+type regionYAML struct {
+	Name      string
+	Interface interfaceList
+}
+
+// regionMapping is the mapping form of a regionYAML.
+type regionMapping struct {
+	Name   string `yaml:"name"`
+	Values struct {
+		Interface interfaceList `yaml:"interface,omitempty"`
+	} `yaml:"values,omitempty"`
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler.
+func (r *regionYAML) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	if err := unmarshal(&r.Name); err == nil {
+		return nil
+	}
+	var m regionMapping
+	if err := unmarshal(&m); err != nil {
+		return errors.New("regions: each entry must be a name or a mapping with a name")
+	}
+	r.Name, r.Interface = m.Name, m.Values.Interface
+	return nil
+}
+
+// MarshalYAML implements yaml.Marshaler.
+func (r regionYAML) MarshalYAML() (interface{}, error) {
+	if len(r.Interface) == 0 {
+		return r.Name, nil
+	}
+	m := regionMapping{Name: r.Name}
+	m.Values.Interface = r.Interface
+	return m, nil
+}
+
+// regions returns the names of every region the cloud entry defines, in
+// region_name and the regions list, without duplicates and in order of
+// appearance.
+func (v *cloudYAML) regions() []string {
+	var rs []string
+	seen := map[string]bool{}
+	add := func(r string) {
+		if r != "" && !seen[r] {
+			seen[r] = true
+			rs = append(rs, r)
+		}
+	}
+	add(v.RegionName)
+	for _, r := range v.Regions {
+		add(r.Name)
+	}
+	return rs
+}
+
+// resolveRegionAliases replaces each symbolic region name in the cloud entry’s
+// region_name and regions list with the region it stands for, as given by the
+// entry’s own region_aliases or else by aliases. Names neither maps are left
+// as they are.
+func (v *cloudYAML) resolveRegionAliases(aliases map[string]string) {
+	if len(v.RegionAliases) == 0 && len(aliases) == 0 {
+		return
+	}
+	resolve := func(r string) string {
+		if concrete, ok := v.RegionAliases[r]; ok {
+			return concrete
+		}
+		if concrete, ok := aliases[r]; ok {
+			return concrete
+		}
+		return r
+	}
+	v.RegionName = resolve(v.RegionName)
+	// The list may be shared with a profile, so it is replaced, not
+	// changed in place.
+	rs := make([]regionYAML, len(v.Regions))
+	for i, r := range v.Regions {
+		r.Name = resolve(r.Name)
+		rs[i] = r
+	}
+	v.Regions = rs
+}
+
+// regionInterfaces returns the interfaces set by entries in the cloud entry’s
+// regions list, keyed by region, or nil if none sets one.
+func (v *cloudYAML) regionInterfaces() map[string][]string {
+	var m map[string][]string
+	for _, r := range v.Regions {
+		if r.Name == "" || len(r.Interface) == 0 {
+			continue
+		}
+		if m == nil {
+			m = map[string][]string{}
+		}
+		m[r.Name] = r.Interface
+	}
+	return m
+}
+
+// AllRegions satisfies the Config interface.
+func (c *configImpl) AllRegions() map[string][]string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	m := map[string][]string{}
+	for k, v := range c.clouds {
+		if v.disabled || len(v.regions) == 0 {
+			continue
+		}
+		rs := append([]string(nil), v.regions...)
+		sort.Strings(rs)
+		m[k] = rs
+	}
+	if len(m) == 0 {
+		return nil
+	}
+	return m
+}
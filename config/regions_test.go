@@ -0,0 +1,123 @@
+package config
+
+import (
+	"github.com/gophercloud/gophercloud"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestEndpointOptsPerRegionInterface(t *testing.T) {
+	conf, err := FromBytes([]byte(`
+clouds:
+  a:
+    region_name: home
+    interface: public
+    regions:
+      - name: home
+        values:
+          interface: internal
+      - name: dr
+        values:
+          interface: [admin, internal]
+      - remote
+    auth: {auth_url: http://a/v3}
+  b:
+    regions: [one, {name: two, values: {interface: internal}}]
+    auth: {auth_url: http://b/v3}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		cloud, region string
+		want          gophercloud.EndpointOpts
+	}{
+		{"a", "", gophercloud.EndpointOpts{Region: "home", Availability: gophercloud.AvailabilityInternal}},
+		{"a", "home", gophercloud.EndpointOpts{Region: "home", Availability: gophercloud.AvailabilityInternal}},
+		{"a", "dr", gophercloud.EndpointOpts{Region: "dr", Availability: gophercloud.AvailabilityAdmin}},
+		{"a", "remote", gophercloud.EndpointOpts{Region: "remote", Availability: gophercloud.AvailabilityPublic}},
+		{"a", "elsewhere", gophercloud.EndpointOpts{Region: "elsewhere", Availability: gophercloud.AvailabilityPublic}},
+		{"b", "one", gophercloud.EndpointOpts{Region: "one"}},
+		{"b", "two", gophercloud.EndpointOpts{Region: "two", Availability: gophercloud.AvailabilityInternal}},
+	} {
+		got, err := conf.EndpointOpts(tt.cloud, tt.region)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("EndpointOpts(%s, %q) = %+v, want %+v", tt.cloud, tt.region, got, tt.want)
+		}
+	}
+}
+
+func TestEndpointOptsBadRegionInterface(t *testing.T) {
+	conf, err := FromBytes([]byte(`
+clouds:
+  a:
+    regions: [{name: r, values: {interface: sideways}}]
+    auth: {auth_url: http://a/v3}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := conf.EndpointOpts("a", "r"); err == nil || !strings.HasPrefix(err.Error(), "config: cloud `a`: ") {
+		t.Errorf("EndpointOpts with an unknown interface error = %v", err)
+	}
+	if _, err := FromBytes([]byte("clouds:\n  a:\n    regions: [[r]]\n    auth: {auth_url: http://a/v3}\n")); err == nil {
+		t.Error("FromBytes with a malformed regions entry succeeded")
+	}
+}
+
+func TestRegionAliases(t *testing.T) {
+	profiles := filepath.Join(t.TempDir(), "clouds-public.yaml")
+	writeFile(t, profiles, `
+public-clouds:
+  corp:
+    region_aliases: {home: CorpEast}
+    auth: {auth_url: http://corp/v3}
+`)
+	conf, err := FromBytes([]byte(`
+clouds:
+  own:
+    region_name: home
+    regions: [home, dr, RegionThree]
+    region_aliases: {home: EntryHome}
+    auth: {auth_url: http://a/v3}
+  shared:
+    region_name: dr
+    regions: [home]
+    auth: {auth_url: http://a/v3}
+  profiled:
+    profile: corp
+    region_name: home
+  unknown:
+    region_name: nowhere
+    auth: {auth_url: http://a/v3}
+`), WithRegionAliases(map[string]string{"home": "OptionHome", "dr": "RegionDR"}), WithPublicCloudsPath(profiles))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]string{
+		"own":      "EntryHome",
+		"shared":   "RegionDR",
+		"profiled": "CorpEast",
+		"unknown":  "nowhere",
+	} {
+		eo, err := conf.EndpointOpts(name, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if eo.Region != want {
+			t.Errorf("EndpointOpts(%s).Region = %s, want %s", name, eo.Region, want)
+		}
+	}
+	regions := conf.AllRegions()
+	if got, want := regions["own"], []string{"EntryHome", "RegionDR", "RegionThree"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("AllRegions()[own] = %v, want %v", got, want)
+	}
+	if got, want := regions["shared"], []string{"OptionHome", "RegionDR"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("AllRegions()[shared] = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,200 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// resolve builds a cloud from the profile it names, if any, its clouds.yaml
+// entry, and its secure.yaml entry, if any, combining every source of its
+// settings in the order of precedence documented for the package: each step
+// below overrides those before it. The selected flag reports whether the cloud
+// is the one environment variables apply to. Relative file paths in the
+// entries are taken relative to dir, if it is not empty.
+//
+// This returns false if the entries define no credentials at all.
+func resolve(name string, profile, v, sv *cloudYAML, dir string, o *options, selected bool) (cloud, bool, error) {
+	if v == nil {
+		if sv == nil {
+			return cloud{}, false, nil
+		}
+		v = &cloudYAML{}
+	}
+
+	// The profile is the base, values in the cloud entry itself override
+	// it, and those in the secure.yaml entry override them.
+	sources := provenance{}
+	if profile != nil {
+		base := profile.copy()
+		sources.record(snapshot{}, base, base.authBlock(), LayerProfile)
+		before := take(base, base.authBlock())
+		mergeCloudYAML(base, v)
+		sources.record(before, base, base.authBlock(), LayerFile)
+		v = base
+	} else {
+		sources.record(snapshot{}, v, v.authBlock(), LayerFile)
+	}
+	if sv != nil {
+		before := take(v, v.authBlock())
+		mergeCloudYAML(v, sv)
+		sources.record(before, v, v.authBlock(), LayerSecure)
+	}
+	if v.Auth == nil && v.Flat.empty() {
+		return cloud{}, false, nil
+	}
+	if dir != "" {
+		for _, p := range []*string{&v.CACert, &v.CAPath, &v.Cert, &v.Key} {
+			if *p != "" && !filepath.IsAbs(*p) && !isInlinePEM(*p) {
+				*p = filepath.Join(dir, *p)
+			}
+		}
+	}
+	a := v.authBlock()
+	if err := checkAliases(a); err != nil {
+		return cloud{}, false, errors.New("cloud `" + name + "`: " + err.Error())
+	}
+	kind := schemaKind(a)
+
+	// Secrets from a secret directory override the entries, and
+	// indirections in them override those in turn.
+	if o.secretDir != "" {
+		before := take(v, a)
+		if err := overlaySecretDir(o.secretDir, name, a); err != nil {
+			return cloud{}, false, errors.New("cloud `" + name + "`: " + err.Error())
+		}
+		sources.record(before, v, a, LayerSecretDir)
+	}
+	before := take(v, a)
+	if err := readFileURIs(a); err != nil {
+		return cloud{}, false, errors.New("cloud `" + name + "`: " + err.Error())
+	}
+	sources.record(before, v, a, LayerIndirection)
+	if a.PasswordEnv != "" {
+		env, ok := os.LookupEnv(a.PasswordEnv)
+		if !ok {
+			msg := "cloud `" + name + "`: password_env variable `" +
+				a.PasswordEnv + "` is not set"
+			return cloud{}, false, errors.New(msg)
+		}
+		a.Password = env
+		sources["auth.password"] = LayerIndirection
+	}
+	if a.PasswordCommand != "" {
+		if a.PasswordEnv != "" {
+			msg := "cloud `" + name + "`: password_env and password_command are mutually exclusive"
+			return cloud{}, false, errors.New(msg)
+		}
+		password, err := runPasswordCommand(a.PasswordCommand, o.commandTimeout)
+		if err != nil {
+			return cloud{}, false, errors.New("cloud `" + name + "`: " + err.Error())
+		}
+		a.Password = password
+		sources["auth.password"] = LayerIndirection
+	}
+
+	if a.TokenFile != "" {
+		token, err := readTokenFile(a.TokenFile)
+		if err != nil {
+			return cloud{}, false, errors.New("cloud `" + name + "`: " + err.Error())
+		}
+		a.Token = token
+		sources["auth.token"] = LayerIndirection
+	}
+
+	if a.KeyringService != "" {
+		account := firstNonEmpty(a.KeyringAccount, name)
+		secret, err := keyringSecret(a.KeyringService, account)
+		if err != nil {
+			return cloud{}, false, errors.New("cloud `" + name + "`: " + err.Error())
+		}
+		a.ApplicationCredentialSecret = secret
+		sources["auth.application_credential_secret"] = LayerIndirection
+	}
+
+	// Environment variables override everything in the file, including
+	// the interfaces set for particular regions.
+	var overrides []EnvOverride
+	if selected {
+		before := take(v, a)
+		overrides = overlayEnv(v, a, o.envPrefix)
+		sources.record(before, v, a, LayerEnv)
+	}
+
+	// Symbolic region names, from wherever they came, are resolved last.
+	v.resolveRegionAliases(o.regionAliases)
+	regionIfaces := v.regionInterfaces()
+	for _, e := range overrides {
+		if e.Field == "interface" {
+			regionIfaces = nil
+		}
+	}
+
+	return cloud{
+		auth:         a.authOptions(),
+		authType:     firstNonEmpty(v.AuthType, o.defaultAuthType),
+		region:       v.RegionName,
+		regions:      v.regions(),
+		description:  v.Description,
+		disabled:     v.Disabled,
+		ifaces:       v.Interface,
+		regionIfaces: regionIfaces,
+		apiVersions:  v.apiVersions(),
+		tokenFile:    a.TokenFile,
+		endpoint:     a.Endpoint,
+		headers:      v.Headers,
+		secretHdrs:   o.secretHeaders,
+		authMethods:  a.AuthMethods,
+		projDomain:   projectDomain{id: a.ProjectDomainID, name: a.ProjectDomainName},
+		extra:        v.Extra,
+		tls: tlsSettings{
+			insecure:   v.Verify != nil && !*v.Verify || v.Insecure != nil && *v.Insecure,
+			cacert:     v.CACert,
+			capath:     v.CAPath,
+			cert:       v.Cert,
+			key:        v.Key,
+			serverName: v.ServerName,
+		},
+		retry: RetryPolicy{
+			ConnectRetries: v.ConnectRetries,
+			ReadRetries:    v.ReadRetries,
+			Backoff:        time.Duration(v.ConnectRetryDelay * float64(time.Second)),
+		},
+		sources:      sources,
+		envOverrides: overrides,
+		schemaKind:   kind,
+	}, true, nil
+}
+
+// checkAliases returns an error if an auth block sets both a legacy field,
+// such as tenant_name, and the field that replaces it, such as project_name,
+// to different values, as only one of them could be used.
+func checkAliases(a *authYAML) error {
+	fs := stringFields(a)
+	for _, legacy := range []string{"tenant_id", "tenant_name"} {
+		current := envAliases[legacy]
+		if *fs[legacy] != "" && *fs[current] != "" && *fs[legacy] != *fs[current] {
+			return errors.New(legacy + " and " + current + " are both set, to different values")
+		}
+	}
+	return nil
+}
+
+// stringFields returns pointers to the string fields of the struct v points
+// to, keyed by their names in clouds.yaml. Inline fields are skipped.
+func stringFields(v interface{}) map[string]*string {
+	fs := map[string]*string{}
+	rv := reflect.ValueOf(v).Elem()
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		f := rt.Field(i)
+		tag := strings.Split(f.Tag.Get("yaml"), ",")[0]
+		if tag != "" && f.Type.Kind() == reflect.String {
+			fs[tag] = rv.Field(i).Addr().Interface().(*string)
+		}
+	}
+	return fs
+}
@@ -0,0 +1,105 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFlatAuthFields(t *testing.T) {
+	conf, err := FromBytes([]byte(`
+clouds:
+  nested:
+    auth:
+      auth_url: http://nested/v3
+      username: un
+      password: pn
+      project_name: prn
+  flat:
+    region_name: RegionOne
+    auth_url: http://flat/v3
+    username: uf
+    password: pf
+    project_name: prf
+  both:
+    username: ignored
+    auth:
+      auth_url: http://both/v3
+      username: ub
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string][3]string{
+		"nested": {"http://nested/v3", "un", "prn"},
+		"flat":   {"http://flat/v3", "uf", "prf"},
+		"both":   {"http://both/v3", "ub", ""},
+	} {
+		opts, err := conf.Get(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := [3]string{opts.IdentityEndpoint, opts.Username, opts.TenantName}; got != want {
+			t.Errorf("Get(%s) = %q, want %q", name, got, want)
+		}
+	}
+	if eo, err := conf.EndpointOpts("flat", ""); err != nil || eo.Region != "RegionOne" {
+		t.Errorf("EndpointOpts(flat) = %+v, %v; want region RegionOne", eo, err)
+	}
+}
+
+func TestFlatAuthFieldsWithSecure(t *testing.T) {
+	conf, err := FromReaders(
+		strings.NewReader("clouds:\n  flat:\n    auth_url: http://flat/v3\n    username: uf\n"),
+		strings.NewReader("clouds:\n  flat:\n    auth:\n      password: secret\n"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts, err := conf.Get("flat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opts.IdentityEndpoint != "http://flat/v3" || opts.Username != "uf" || opts.Password != "secret" {
+		t.Errorf("Get(flat) with a nested secure.yaml entry = %+v", opts)
+	}
+}
+
+func TestTenantProjectConflict(t *testing.T) {
+	for _, tt := range []struct {
+		auth, want string
+	}{
+		{"{auth_url: http://a/v3, tenant_name: x, project_name: y}", "cloud `a`: tenant_name and project_name are both set, to different values"},
+		{"{auth_url: http://a/v3, tenant_id: x, project_id: y}", "cloud `a`: tenant_id and project_id are both set, to different values"},
+	} {
+		_, err := FromBytes([]byte("clouds:\n  a:\n    auth: " + tt.auth + "\n"))
+		if parseErr, ok := err.(*ParseError); !ok || parseErr.Err.Error() != tt.want {
+			t.Errorf("FromBytes with %s error = %v, want %s", tt.auth, err, tt.want)
+		}
+	}
+
+	// A conflict made by merging secure.yaml is caught too.
+	_, err := FromReaders(
+		strings.NewReader("clouds:\n  a:\n    auth: {auth_url: http://a/v3, project_name: y}\n"),
+		strings.NewReader("clouds:\n  a:\n    auth: {tenant_name: x}\n"),
+	)
+	if _, ok := err.(*ParseError); !ok {
+		t.Errorf("FromReaders with a conflict across files error = %v, want a *ParseError", err)
+	}
+}
+
+func TestTenantProjectMatch(t *testing.T) {
+	for _, auth := range []string{
+		"{auth_url: http://a/v3, tenant_name: x, project_name: x, tenant_id: i, project_id: i}",
+		"{auth_url: http://a/v3, tenant_name: x, tenant_id: i}",
+		"{auth_url: http://a/v3, project_name: x, project_id: i}",
+	} {
+		conf, err := FromBytes([]byte("clouds:\n  a:\n    auth: " + auth + "\n"))
+		if err != nil {
+			t.Errorf("FromBytes with %s: %v", auth, err)
+			continue
+		}
+		if opts, err := conf.Get("a"); err != nil || opts.TenantName != "x" || opts.TenantID != "i" {
+			t.Errorf("Get(a) with %s = %+v, %v", auth, opts, err)
+		}
+	}
+}
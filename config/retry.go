@@ -0,0 +1,42 @@
+package config
+
+import (
+	"time"
+)
+
+// RetryPolicy describes how requests to a cloud should be retried, as
+// declared by its connect_retries, read_retries, and connect_retry_delay
+// fields. The zero value means no retries.
+//
+// This package does not retry requests itself; callers apply the policy to
+// their own transport or to a gophercloud RetryFunc.
+type RetryPolicy struct {
+	// ConnectRetries is how many times to retry a request that fails to
+	// connect.
+	ConnectRetries int
+
+	// ReadRetries is how many times to retry a request that connects but
+	// then fails, such as by timing out while reading the response.
+	ReadRetries int
+
+	// Backoff is the delay before the first retry. (See Delay.)
+	Backoff time.Duration
+}
+
+// Delay returns how long to wait before the given retry, counting from 1. The
+// delay starts at Backoff and doubles with each retry.
+func (p RetryPolicy) Delay(retry int) time.Duration {
+	if retry < 1 {
+		return 0
+	}
+	return p.Backoff << uint(retry-1)
+}
+
+// RetryPolicy satisfies the Config interface.
+func (c *configImpl) RetryPolicy(name string) (RetryPolicy, error) {
+	v, err := c.cloud(name)
+	if err != nil {
+		return RetryPolicy{}, err
+	}
+	return v.retry, nil
+}
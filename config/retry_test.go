@@ -0,0 +1,54 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRetryPolicy(t *testing.T) {
+	conf, err := FromBytes([]byte(`
+clouds:
+  flaky:
+    connect_retries: 3
+    read_retries: 2
+    connect_retry_delay: 0.5
+    auth: {auth_url: http://a/v3}
+  steady:
+    auth: {auth_url: http://b/v3}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]RetryPolicy{
+		"flaky":  {ConnectRetries: 3, ReadRetries: 2, Backoff: 500 * time.Millisecond},
+		"steady": {},
+	} {
+		got, err := conf.RetryPolicy(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Errorf("RetryPolicy(%s) = %+v, want %+v", name, got, want)
+		}
+	}
+	if _, err := conf.RetryPolicy("missing"); err == nil {
+		t.Error("RetryPolicy of an undefined cloud succeeded")
+	}
+}
+
+func TestRetryPolicyDelay(t *testing.T) {
+	p := RetryPolicy{Backoff: 100 * time.Millisecond}
+	for retry, want := range map[int]time.Duration{
+		0: 0,
+		1: 100 * time.Millisecond,
+		2: 200 * time.Millisecond,
+		4: 800 * time.Millisecond,
+	} {
+		if got := p.Delay(retry); got != want {
+			t.Errorf("Delay(%d) = %v, want %v", retry, got, want)
+		}
+	}
+	if got := (RetryPolicy{}).Delay(3); got != 0 {
+		t.Errorf("Delay of the zero policy = %v, want 0", got)
+	}
+}
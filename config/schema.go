@@ -0,0 +1,58 @@
+package config
+
+// The kinds of entry SchemaKind reports.
+const (
+	SchemaV2      = "v2"
+	SchemaV3      = "v3"
+	SchemaMixed   = "mixed"
+	SchemaUnknown = "unknown"
+)
+
+// SchemaKind satisfies the Config interface.
+func (c *configImpl) SchemaKind(name string) (string, error) {
+	v, err := c.cloud(name)
+	if err != nil {
+		return "", err
+	}
+	if v.schemaKind == "" {
+		// The cloud did not come from a file, as with FromMap.
+		return SchemaUnknown, nil
+	}
+	return v.schemaKind, nil
+}
+
+// SchemaKinds summarizes SchemaKind across the enabled clouds of conf,
+// returning their sorted names keyed by kind, for a migration assistant to
+// list the entries still using the legacy conventions. Kinds no cloud has
+// are left out.
+func SchemaKinds(conf Config) map[string][]string {
+	kinds := map[string][]string{}
+	for _, name := range conf.Names() {
+		kind, err := conf.SchemaKind(name)
+		if err != nil {
+			// The cloud was removed since listing the names.
+			continue
+		}
+		kinds[kind] = append(kinds[kind], name)
+	}
+	return kinds
+}
+
+// schemaKind classifies an auth block by the fields characteristic of the
+// identity v2 and v3 conventions that it sets.
+func schemaKind(a *authYAML) string {
+	v2 := a.TenantName != "" || a.TenantID != ""
+	v3 := a.ProjectName != "" || a.ProjectID != "" ||
+		a.DomainName != "" || a.DomainID != "" ||
+		a.UserDomainName != "" || a.UserDomainID != "" ||
+		a.ProjectDomainName != "" || a.ProjectDomainID != ""
+	switch {
+	case v2 && v3:
+		return SchemaMixed
+	case v2:
+		return SchemaV2
+	case v3:
+		return SchemaV3
+	}
+	return SchemaUnknown
+}
@@ -0,0 +1,105 @@
+package config
+
+import (
+	"errors"
+	"github.com/gophercloud/gophercloud"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// GetV3Scoped satisfies the Config interface.
+func (c *configImpl) GetV3Scoped(name, projectName, projectDomainName string) (gophercloud.AuthOptions, error) {
+	v, err := c.cloudWithSecrets(name)
+	if err != nil {
+		return gophercloud.AuthOptions{}, err
+	}
+	if v.identityVersion() == "2" {
+		s := "config: cloud `" + name + "` uses identity v2, which cannot scope by project domain"
+		return gophercloud.AuthOptions{}, errors.New(s)
+	}
+	opts := cloneAuthOptions(v.auth)
+	scope := &gophercloud.AuthScope{ProjectName: projectName, DomainName: projectDomainName}
+	if projectDomainName == "" {
+		scope.DomainID, scope.DomainName = opts.DomainID, opts.DomainName
+	}
+	if scope.DomainID == "" && scope.DomainName == "" {
+		s := "config: cloud `" + name + "` has no domain to scope project `" + projectName + "` by"
+		return gophercloud.AuthOptions{}, errors.New(s)
+	}
+	opts.TenantID, opts.TenantName = "", projectName
+	opts.Scope = scope
+	return opts, nil
+}
+
+// GetUnscoped satisfies the Config interface.
+func (c *configImpl) GetUnscoped(name string) (gophercloud.AuthOptions, error) {
+	v, err := c.cloudWithSecrets(name)
+	if err != nil {
+		return gophercloud.AuthOptions{}, err
+	}
+	switch t := v.effectiveAuthType(); t {
+	case "password", "v2password", "v3password", "token", "v2token", "v3token", "v3totp", "v3multifactor":
+	default:
+		s := "config: cloud `" + name + "` uses auth_type `" + t + "`, which cannot authenticate unscoped"
+		return gophercloud.AuthOptions{}, errors.New(s)
+	}
+	opts := cloneAuthOptions(v.auth)
+	opts.TenantID, opts.TenantName = "", ""
+	opts.Scope = nil
+	return opts, nil
+}
+
+// identityVersion returns the major identity API version a cloud uses, "2" or
+// "3", or an empty string if it cannot be told from the config, in which case
+// gophercloud discovers it from the server. The first of these that applies
+// decides:
+//
+//  1. identity_api_version
+//  2. a v2 or v3 prefix on auth_type, such as v3password
+//  3. a trailing /v2.0 or /v3 on auth_url’s path
+//  4. domain or application credential fields, which only v3 has
+func (v cloud) identityVersion() string {
+	if iv := v.apiVersions["identity"]; iv != "" {
+		return strings.SplitN(iv, ".", 2)[0]
+	}
+	switch {
+	case strings.HasPrefix(v.authType, "v2"):
+		return "2"
+	case strings.HasPrefix(v.authType, "v3"):
+		return "3"
+	}
+	if u, err := url.Parse(v.auth.IdentityEndpoint); err == nil {
+		switch strings.TrimSuffix(u.Path, "/") {
+		case "/v2.0", "/v2":
+			return "2"
+		case "/v3":
+			return "3"
+		}
+	}
+	a := v.auth
+	if a.DomainID != "" || a.DomainName != "" || a.Scope != nil ||
+		v.projectDomainID() != "" || v.projectDomainName() != "" ||
+		a.ApplicationCredentialID != "" || a.ApplicationCredentialName != "" {
+		return "3"
+	}
+	return ""
+}
+
+// checkIdentityVersions returns an error naming every enabled cloud that would
+// authenticate with an identity API version below minVersion, or nil if there
+// are none. Clouds whose version cannot be told in advance are allowed.
+func checkIdentityVersions(clouds map[string]cloud, minVersion string) error {
+	var names []string
+	for k, v := range clouds {
+		if iv := v.identityVersion(); !v.disabled && iv != "" && iv < minVersion {
+			names = append(names, "`"+k+"`")
+		}
+	}
+	if len(names) == 0 {
+		return nil
+	}
+	sort.Strings(names)
+	msg := "clouds " + strings.Join(names, ", ") + " authenticate with an identity API version below " + minVersion
+	return errors.New(msg)
+}
@@ -0,0 +1,50 @@
+package config
+
+import (
+	"testing"
+)
+
+const mixedIdentityClouds = `
+clouds:
+  v3:
+    identity_api_version: 3
+    auth: {auth_url: http://a/v3}
+  v2-by-version:
+    identity_api_version: 2
+    auth: {auth_url: http://a/v3}
+  v2-by-type:
+    auth_type: v2password
+    auth: {auth_url: http://a/}
+  v2-by-url:
+    auth: {auth_url: http://a/v2.0}
+  v3-by-domain:
+    auth: {auth_url: http://a/, user_domain_name: Default}
+  negotiated:
+    auth: {auth_url: http://a/}
+  v2-disabled:
+    disabled: true
+    auth: {auth_url: http://a/v2.0}
+`
+
+func TestWithMinIdentityVersion(t *testing.T) {
+	_, err := FromBytes([]byte(mixedIdentityClouds), WithMinIdentityVersion("3"))
+	parseErr, ok := err.(*ParseError)
+	want := "clouds `v2-by-type`, `v2-by-url`, `v2-by-version` authenticate with an identity API version below 3"
+	if !ok || parseErr.Err.Error() != want {
+		t.Fatalf("FromBytes with a mixed file error = %v, want %s", err, want)
+	}
+
+	for _, opts := range [][]Option{nil, {WithMinIdentityVersion("2")}} {
+		conf, err := FromBytes([]byte(mixedIdentityClouds), opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := len(conf.Names()); got != 6 {
+			t.Errorf("FromBytes loaded %d enabled clouds, want 6", got)
+		}
+	}
+
+	if _, err := FromBytes([]byte(mixedIdentityClouds), WithMinIdentityVersion("4")); err == nil {
+		t.Error("WithMinIdentityVersion(4) succeeded")
+	}
+}
@@ -0,0 +1,64 @@
+package config
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// secretFields lists the auth fields that hold secrets.
+var secretFields = []string{
+	"password",
+	"token",
+	"application_credential_secret",
+}
+
+// fileURIPrefix marks a secret field value that names a file holding the
+// secret, as in file:///run/secrets/os_password.
+const fileURIPrefix = "file://"
+
+// readFileURIs replaces each secret field of an auth block whose value is a
+// file URI with the contents of the file, without trailing whitespace. Errors
+// name only the field and the file, so they never reveal a secret.
+func readFileURIs(a *authYAML) error {
+	fs := stringFields(a)
+	for _, field := range secretFields {
+		p := fs[field]
+		if !strings.HasPrefix(*p, fileURIPrefix) {
+			continue
+		}
+		u, err := url.Parse(*p)
+		if err != nil || u.Host != "" && u.Host != "localhost" || u.Path == "" {
+			return errors.New(field + ": invalid file URI `" + *p + "`")
+		}
+		b, err := ioutil.ReadFile(filepath.FromSlash(u.Path))
+		if err != nil {
+			return errors.New(field + ": cannot read secret: " + err.Error())
+		}
+		*p = strings.TrimRight(string(b), " \t\r\n")
+	}
+	return nil
+}
+
+// overlaySecretDir sets each secret field of an auth block for which the
+// directory holds a file named <cloud>.<field>, such as prod.password, to the
+// file’s contents without its trailing newline. Errors name only the file, so
+// they never reveal a secret.
+func overlaySecretDir(dir, name string, a *authYAML) error {
+	fs := stringFields(a)
+	for _, field := range secretFields {
+		path := filepath.Join(dir, name+"."+field)
+		b, err := ioutil.ReadFile(path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return errors.New("cannot read secret: " + err.Error())
+		}
+		*fs[field] = strings.TrimRight(string(b), "\r\n")
+	}
+	return nil
+}
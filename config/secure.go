@@ -0,0 +1,76 @@
+package config
+
+import (
+	"io"
+	"reflect"
+)
+
+// FromReaders returns an initialized *Config from clouds.yaml content read
+// from clouds and secure.yaml content read from secure, without touching the
+// filesystem. Either reader may be nil. Like FromReader, this transparently
+// decompresses gzip content.
+//
+// As with OpenStack’s own tools, secure.yaml has the same layout as
+// clouds.yaml and is meant to hold the secrets kept out of it. Each field a
+// cloud sets in secure.yaml overrides the same field of its clouds.yaml
+// entry, and a cloud defined only in secure.yaml is used as is. Secrets
+// supplied by other means, such as password_env, still take precedence over
+// both.
+func FromReaders(clouds, secure io.Reader, opts ...Option) (Config, error) {
+	o, err := newOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+	var b, sb []byte
+	if clouds != nil {
+		if b, err = readAll(clouds, "", o); err != nil {
+			return nil, err
+		}
+	}
+	if secure != nil {
+		if sb, err = readAll(secure, "", o); err != nil {
+			return nil, err
+		}
+		if sb == nil {
+			sb = []byte{}
+		}
+	}
+	return parseWithSecure(b, "", sb, "", "", o)
+}
+
+// authBlock returns the auth fields of a cloud entry: its auth block, or the
+// fields placed directly in the entry if it has none.
+func (v *cloudYAML) authBlock() *authYAML {
+	if v.Auth != nil {
+		return v.Auth
+	}
+	return &v.Flat
+}
+
+// mergeCloudYAML overrides the fields of a cloud entry with those set in
+// another, such as its secure.yaml entry. The other entry’s auth fields are
+// merged into the entry’s auth block, wherever either places them.
+func mergeCloudYAML(dst, src *cloudYAML) {
+	if dst.Auth == nil && src.Auth != nil {
+		a := dst.Flat
+		dst.Auth = &a
+	}
+	mergeFields(dst.authBlock(), src.authBlock())
+	mergeFields(dst, src)
+}
+
+// mergeFields sets each field of the struct dst points to, other than the
+// auth fields of a cloud entry, to the same field of src if that is set.
+func mergeFields(dst, src interface{}) {
+	dv := reflect.ValueOf(dst).Elem()
+	sv := reflect.ValueOf(src).Elem()
+	for i := 0; i < dv.NumField(); i++ {
+		switch dv.Type().Field(i).Name {
+		case "Auth", "Flat":
+			continue
+		}
+		if f := sv.Field(i); !f.IsZero() {
+			dv.Field(i).Set(f)
+		}
+	}
+}
@@ -0,0 +1,154 @@
+package config
+
+import (
+	"errors"
+	"github.com/gophercloud/gophercloud"
+	"gopkg.in/yaml.v2"
+	yaml3 "gopkg.in/yaml.v3"
+	"os"
+	"path/filepath"
+)
+
+// lastValue returns the value of the last occurrence of a key in a mapping
+// node, which is the one a full load keeps, or nil if the key is not present.
+func lastValue(m *yaml3.Node, key string) *yaml3.Node {
+	var v *yaml3.Node
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			v = m.Content[i+1]
+		}
+	}
+	return v
+}
+
+// unalias returns a copy of n with every alias replaced by a copy of the node
+// it refers to, so that n can be encoded without the rest of its document.
+func unalias(n *yaml3.Node) *yaml3.Node {
+	for n.Kind == yaml3.AliasNode {
+		n = n.Alias
+	}
+	c := *n
+	c.Anchor = ""
+	c.Content = make([]*yaml3.Node, len(n.Content))
+	for i, e := range n.Content {
+		c.Content[i] = unalias(e)
+	}
+	return &c
+}
+
+// decodeNode decodes n into v as yaml.v2 would decode the same YAML, so that
+// entries decoded one at a time match those of a full load.
+func decodeNode(n *yaml3.Node, v interface{}) error {
+	b, err := yaml3.Marshal(unalias(n))
+	if err != nil {
+		return err
+	}
+	return yaml.Unmarshal(b, v)
+}
+
+// selectClouds decodes only the named entries of a clouds section.
+func selectClouds(n *yaml3.Node, names []string) (map[string]*cloudYAML, error) {
+	clouds := map[string]*cloudYAML{}
+	if n == nil {
+		return clouds, nil
+	}
+	for n.Kind == yaml3.AliasNode {
+		n = n.Alias
+	}
+	if n.Kind == yaml3.ScalarNode && n.Tag == "!!null" {
+		return clouds, nil
+	}
+	if n.Kind != yaml3.MappingNode {
+		return nil, errors.New("`clouds` is not a mapping")
+	}
+	for _, name := range names {
+		e := lastValue(n, name)
+		if e == nil {
+			continue
+		}
+		var v *cloudYAML
+		if err := decodeNode(e, &v); err != nil {
+			return nil, err
+		}
+		if v != nil {
+			clouds[name] = v
+		}
+	}
+	return clouds, nil
+}
+
+// LoadClouds returns the options for only the named clouds in a clouds.yaml
+// file, for callers that need a few clouds from a very large file. The file
+// is parsed into a document tree, but only the entries for the named clouds
+// are decoded from it, which saves the memory a full load spends decoding
+// and resolving the others. The clouds returned are exactly those FromFile
+// would give with no options. This returns an error if the file cannot be
+// read or parsed, or if a named cloud is not defined or is disabled.
+func LoadClouds(path string, names ...string) (map[string]gophercloud.AuthOptions, error) {
+	o, err := newOptions(nil)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, &FileError{path, err}
+	}
+	defer f.Close()
+	b, err := readAll(f, path, o)
+	if err != nil {
+		return nil, err
+	}
+	if b, err = toUTF8(b); err != nil {
+		return nil, &ParseError{path, err}
+	}
+	var doc yaml3.Node
+	if err := yaml3.Unmarshal(b, &doc); err != nil {
+		return nil, &ParseError{path, err}
+	}
+	var sel map[string]*cloudYAML
+	var templates map[string]*authYAML
+	if len(doc.Content) == 1 && doc.Content[0].Kind == yaml3.MappingNode {
+		top := doc.Content[0]
+		if sel, err = selectClouds(lastValue(top, "clouds"), names); err != nil {
+			return nil, &ParseError{path, err}
+		}
+		if t := lastValue(top, "auth_templates"); t != nil {
+			if err := decodeNode(t, &templates); err != nil {
+				return nil, &ParseError{path, err}
+			}
+		}
+	}
+	if err := applyAuthTemplates(sel, templates); err != nil {
+		return nil, &ParseError{path, err}
+	}
+
+	var profiles map[string]*cloudYAML
+	cs := map[string]gophercloud.AuthOptions{}
+	for _, name := range names {
+		v := sel[name]
+		if v == nil || v.Disabled {
+			return nil, errors.New("config: cloud `" + name + "` not found")
+		}
+		var profile *cloudYAML
+		if v.Profile != "" {
+			if profiles == nil {
+				if profiles, err = loadProfiles(o); err != nil {
+					return nil, err
+				}
+			}
+			if profile = profiles[v.Profile]; profile == nil {
+				msg := "cloud `" + name + "`: profile `" + v.Profile + "` is not defined in " + publicCloudsFile
+				return nil, &ParseError{path, errors.New(msg)}
+			}
+		}
+		cl, ok, err := resolve(name, profile, v, nil, filepath.Dir(path), o, false)
+		if err != nil {
+			return nil, &ParseError{path, err}
+		}
+		if !ok {
+			return nil, errors.New("config: cloud `" + name + "` not found")
+		}
+		cs[name] = cl.auth
+	}
+	return cs, nil
+}
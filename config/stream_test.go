@@ -0,0 +1,144 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const streamClouds = `
+base: &base
+  region_name: RegionOne
+  auth: &creds
+    auth_url: https://keystone.example.com/v3
+    username: admin
+    password: s3cret
+auth_templates:
+  shared:
+    auth_url: https://shared.example.com/v3
+    user_domain_name: Default
+clouds:
+  merged:
+    <<: *base
+    region_name: RegionTwo
+  aliased:
+    auth: *creds
+  templated:
+    auth_ref: shared
+    auth:
+      username: bob
+      password: pw
+  flat:
+    auth_url: https://flat.example.com/v3
+    username: carol
+    password: pw
+  twice:
+    auth:
+      auth_url: https://first.example.com/v3
+  twice:
+    auth:
+      auth_url: https://second.example.com/v3
+      username: dave
+      password: pw
+  off:
+    disabled: yes
+    auth:
+      auth_url: https://off.example.com/v3
+`
+
+func TestLoadCloudsMatchesFullLoad(t *testing.T) {
+	isolateSearch(t)
+	path := filepath.Join(t.TempDir(), "clouds.yaml")
+	writeFile(t, path, streamClouds)
+	conf, err := FromFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := []string{"merged", "aliased", "templated", "flat", "twice"}
+	got, err := LoadClouds(path, names...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != len(names) {
+		t.Errorf("LoadClouds returned %d clouds, want %d", len(got), len(names))
+	}
+	for _, name := range names {
+		want, err := conf.Get(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got[name], want) {
+			t.Errorf("LoadClouds cloud %s = %+v, want %+v", name, got[name], want)
+		}
+	}
+}
+
+func TestLoadCloudsErrors(t *testing.T) {
+	isolateSearch(t)
+	dir := t.TempDir()
+	path := filepath.Join(dir, "clouds.yaml")
+	writeFile(t, path, streamClouds)
+	for _, name := range []string{"off", "missing"} {
+		_, err := LoadClouds(path, "merged", name)
+		if want := "config: cloud `" + name + "` not found"; err == nil || err.Error() != want {
+			t.Errorf("LoadClouds(%s) error = %v, want %s", name, err, want)
+		}
+	}
+
+	bad := filepath.Join(dir, "bad.yaml")
+	for doc, want := range map[string]string{
+		"clouds: [a, b]\n":                     "`clouds` is not a mapping",
+		"clouds:\n  a:\n    auth_ref: nope\n":  "auth_ref `nope` is not defined",
+		"clouds:\n  a:\n    regions: {x: 1}\n": "cannot unmarshal",
+		"clouds:\n  a: {auth: {auth_url: x}\n": "did not find expected",
+	} {
+		writeFile(t, bad, doc)
+		_, err := LoadClouds(bad, "a")
+		if _, ok := err.(*ParseError); !ok || !strings.Contains(err.Error(), want) {
+			t.Errorf("LoadClouds(%q) error = %v, want a *ParseError containing %q", doc, err, want)
+		}
+	}
+
+	if _, err := LoadClouds(filepath.Join(dir, "none.yaml"), "a"); err == nil {
+		t.Error("LoadClouds of a missing file succeeded")
+	} else if _, ok := err.(*FileError); !ok {
+		t.Errorf("LoadClouds of a missing file error = %T, want *FileError", err)
+	}
+}
+
+// benchFile writes a clouds.yaml file defining n clouds and returns its path.
+func benchFile(b *testing.B, n int) string {
+	path := filepath.Join(b.TempDir(), "clouds.yaml")
+	if err := os.WriteFile(path, []byte(benchClouds(n)), 0o600); err != nil {
+		b.Fatal(err)
+	}
+	return path
+}
+
+func BenchmarkLoadClouds(b *testing.B) {
+	path := benchFile(b, 2000)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := LoadClouds(path, "cloud1000"); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkLoadCloudsFullLoad(b *testing.B) {
+	path := benchFile(b, 2000)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		conf, err := FromFile(path)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if _, err := conf.Get("cloud1000"); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
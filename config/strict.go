@@ -0,0 +1,131 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"gopkg.in/yaml.v2"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// checkDuplicateKeys returns an error naming every key that appears more than
+// once in the same mapping within the clouds section of a document, or nil if
+// there are none. Unlike the ordinary decoder, which keeps the last of
+// duplicate keys, this sees every occurrence.
+func checkDuplicateKeys(b []byte, cloudsKey string) error {
+	var doc yaml.MapSlice
+	if err := yaml.Unmarshal(b, &doc); err != nil {
+		return err
+	}
+	var problems []string
+	for _, k := range duplicates(doc) {
+		if k == cloudsKey {
+			problems = append(problems, "duplicate key `"+k+"`")
+		}
+	}
+	for _, item := range doc {
+		if fmt.Sprint(item.Key) != cloudsKey {
+			continue
+		}
+		section, _ := item.Value.(yaml.MapSlice)
+		for _, k := range duplicates(section) {
+			problems = append(problems, "duplicate cloud `"+k+"`")
+		}
+		for _, c := range section {
+			name := fmt.Sprint(c.Key)
+			entry, _ := c.Value.(yaml.MapSlice)
+			for _, k := range duplicatePaths(entry, "") {
+				problems = append(problems, "cloud `"+name+"`: duplicate key `"+k+"`")
+			}
+		}
+	}
+	if len(problems) > 0 {
+		return errors.New(strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// duplicates returns each key that appears more than once in m.
+func duplicates(m yaml.MapSlice) []string {
+	seen := map[string]int{}
+	var dups []string
+	for _, item := range m {
+		k := fmt.Sprint(item.Key)
+		seen[k]++
+		if seen[k] == 2 {
+			dups = append(dups, k)
+		}
+	}
+	return dups
+}
+
+// duplicatePaths returns the dotted path of each duplicate key in m and in the
+// mappings nested within it.
+func duplicatePaths(m yaml.MapSlice, prefix string) []string {
+	var paths []string
+	for _, k := range duplicates(m) {
+		paths = append(paths, prefix+k)
+	}
+	for _, item := range m {
+		if nested, ok := item.Value.(yaml.MapSlice); ok {
+			paths = append(paths, duplicatePaths(nested, prefix+fmt.Sprint(item.Key)+".")...)
+		}
+	}
+	return paths
+}
+
+// checkUnknownKeys returns an error naming every key in a cloud entry or its
+// auth block, within the clouds section of a document, that is not part of the
+// clouds.yaml schema, or nil if there are none.
+func checkUnknownKeys(b []byte, cloudsKey string) error {
+	doc := map[string]interface{}{}
+	if err := yaml.Unmarshal(b, &doc); err != nil {
+		return err
+	}
+	section, _ := doc[cloudsKey].(map[interface{}]interface{})
+	cloudKeys := schemaKeys(reflect.TypeOf(cloudYAML{}))
+	authKeys := schemaKeys(reflect.TypeOf(authYAML{}))
+	var problems []string
+	for name, entry := range section {
+		fields, _ := entry.(map[interface{}]interface{})
+		for k, value := range fields {
+			if !cloudKeys[fmt.Sprint(k)] {
+				problems = append(problems, "cloud `"+fmt.Sprint(name)+"`: unknown key `"+fmt.Sprint(k)+"`")
+			}
+			if k != "auth" {
+				continue
+			}
+			auth, _ := value.(map[interface{}]interface{})
+			for k := range auth {
+				if !authKeys[fmt.Sprint(k)] {
+					problems = append(problems, "cloud `"+fmt.Sprint(name)+"`: unknown key `auth."+fmt.Sprint(k)+"`")
+				}
+			}
+		}
+	}
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return errors.New(strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// schemaKeys returns the keys the YAML decoder maps to fields of a struct
+// type, including those of inline structs.
+func schemaKeys(t reflect.Type) map[string]bool {
+	keys := map[string]bool{}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := strings.Split(f.Tag.Get("yaml"), ",")
+		switch {
+		case tag[0] != "":
+			keys[tag[0]] = true
+		case strings.Contains(f.Tag.Get("yaml"), "inline") && f.Type.Kind() == reflect.Struct:
+			for k := range schemaKeys(f.Type) {
+				keys[k] = true
+			}
+		}
+	}
+	return keys
+}
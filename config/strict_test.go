@@ -0,0 +1,55 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+const duplicateClouds = `
+clouds:
+  a:
+    auth:
+      auth_url: http://first/v3
+      auth_url: http://second/v3
+      username: u
+  b:
+    region_name: one
+    region_name: two
+    auth: {auth_url: http://b/v3}
+`
+
+func TestDuplicateKeysLastWins(t *testing.T) {
+	conf, err := FromBytes([]byte(duplicateClouds))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opts, err := conf.Get("a"); err != nil || opts.IdentityEndpoint != "http://second/v3" {
+		t.Errorf("Get(a) with duplicate auth_url = %+v, %v; want the last auth_url", opts, err)
+	}
+	if eo, err := conf.EndpointOpts("b", ""); err != nil || eo.Region != "two" {
+		t.Errorf("EndpointOpts(b) with duplicate region_name = %+v, %v; want region two", eo, err)
+	}
+}
+
+func TestWithRejectDuplicateKeys(t *testing.T) {
+	_, err := FromBytes([]byte(duplicateClouds), WithRejectDuplicateKeys())
+	if _, ok := err.(*ParseError); !ok {
+		t.Fatalf("FromBytes with duplicate keys error = %v, want a *ParseError", err)
+	}
+	for _, want := range []string{
+		"cloud `a`: duplicate key `auth.auth_url`",
+		"cloud `b`: duplicate key `region_name`",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("FromBytes with duplicate keys error = %v, want it to mention %s", err, want)
+		}
+	}
+
+	_, err = FromBytes([]byte(validClouds+"  a:\n    auth: {auth_url: http://x/v3}\n"), WithRejectDuplicateKeys())
+	if err == nil || !strings.Contains(err.Error(), "duplicate cloud `a`") {
+		t.Errorf("FromBytes with a duplicate cloud error = %v, want it to mention the cloud", err)
+	}
+	if _, err := FromBytes([]byte(validClouds), WithRejectDuplicateKeys()); err != nil {
+		t.Errorf("FromBytes without duplicates: %v", err)
+	}
+}
@@ -0,0 +1,52 @@
+package config
+
+import (
+	"io"
+	"net/url"
+	"strings"
+	"text/tabwriter"
+)
+
+// FormatTable satisfies the Config interface.
+func (c *configImpl) FormatTable(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	// The tabwriter may write to w at any row, so the first error is kept
+	// and returned in place of Flush's.
+	var werr error
+	row := func(cols ...string) {
+		for i, col := range cols {
+			if col == "" {
+				cols[i] = "-"
+			}
+		}
+		if _, err := io.WriteString(tw, strings.Join(cols, "\t")+"\n"); err != nil && werr == nil {
+			werr = err
+		}
+	}
+	row("NAME", "AUTH_URL", "REGION", "INTERFACE", "AUTH_TYPE")
+	for _, name := range c.Names() {
+		v, err := c.cloud(name)
+		if err != nil {
+			// The cloud was removed since listing the names.
+			continue
+		}
+		row(name, redactURL(v.auth.IdentityEndpoint), v.region, strings.Join(v.ifaces, ","), v.effectiveAuthType())
+	}
+	if err := tw.Flush(); werr == nil {
+		werr = err
+	}
+	return werr
+}
+
+// redactURL returns s with the password of any user information in it
+// masked, or s unchanged if it is not a URL with one.
+func redactURL(s string) string {
+	u, err := url.Parse(s)
+	if err != nil || u.User == nil {
+		return s
+	}
+	if _, ok := u.User.Password(); !ok {
+		return s
+	}
+	return u.Redacted()
+}
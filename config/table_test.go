@@ -0,0 +1,68 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestFormatTable(t *testing.T) {
+	conf, err := FromBytes([]byte(`
+clouds:
+  b:
+    region_name: RegionOne
+    interface: [internal, public]
+    auth: {auth_url: "http://admin:s3cret@b/v3", username: u, password: p}
+  a:
+    auth_type: token
+    auth: {auth_url: http://a/v3, token: t}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b bytes.Buffer
+	if err := conf.FormatTable(&b); err != nil {
+		t.Fatal(err)
+	}
+	want := `NAME  AUTH_URL                 REGION     INTERFACE        AUTH_TYPE
+a     http://a/v3              -          -                token
+b     http://admin:xxxxx@b/v3  RegionOne  internal,public  password
+`
+	if b.String() != want {
+		t.Errorf("FormatTable =\n%s\nwant\n%s", b.String(), want)
+	}
+}
+
+// failingWriter fails every write after the first n bytes.
+type failingWriter struct {
+	n   int
+	err error
+}
+
+func (w *failingWriter) Write(p []byte) (int, error) {
+	if len(p) > w.n {
+		n := w.n
+		w.n = 0
+		return n, w.err
+	}
+	w.n -= len(p)
+	return len(p), nil
+}
+
+func TestFormatTableWriteError(t *testing.T) {
+	var clouds bytes.Buffer
+	clouds.WriteString("clouds:\n")
+	for _, name := range []string{"a", "b", "c"} {
+		clouds.WriteString("  " + name + ":\n    auth: {auth_url: http://" + name + "/v3}\n")
+	}
+	conf, err := FromBytes(clouds.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	errFull := errors.New("disk full")
+	for _, n := range []int{0, 10, 100} {
+		if err := conf.FormatTable(&failingWriter{n: n, err: errFull}); err != errFull {
+			t.Errorf("FormatTable to a writer failing after %d bytes = %v, want %v", n, err, errFull)
+		}
+	}
+}
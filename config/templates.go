@@ -0,0 +1,25 @@
+package config
+
+import (
+	"errors"
+)
+
+// applyAuthTemplates merges the auth template each cloud entry names with
+// auth_ref under the entry’s own auth fields, so the entry’s fields win. The
+// result becomes the entry’s auth block. This returns an error if an entry
+// names a template that is not defined.
+func applyAuthTemplates(clouds map[string]*cloudYAML, templates map[string]*authYAML) error {
+	for k, v := range clouds {
+		if v == nil || v.AuthRef == "" {
+			continue
+		}
+		t := templates[v.AuthRef]
+		if t == nil {
+			return errors.New("cloud `" + k + "`: auth_ref `" + v.AuthRef + "` is not defined in auth_templates")
+		}
+		a := *t
+		mergeFields(&a, v.authBlock())
+		v.Auth = &a
+	}
+	return nil
+}
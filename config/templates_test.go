@@ -0,0 +1,77 @@
+package config
+
+import (
+	"testing"
+)
+
+func TestAuthTemplates(t *testing.T) {
+	conf, err := FromBytes([]byte(`
+auth_templates:
+  corp:
+    auth_url: http://corp/v3
+    username: template-user
+    user_domain_name: Corp
+    project_domain_name: Corp
+clouds:
+  plain:
+    auth_ref: corp
+  override:
+    auth_ref: corp
+    auth:
+      username: entry-user
+      password: pw
+      project_name: proj
+  flat:
+    auth_ref: corp
+    username: flat-user
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string][4]string{
+		"plain":    {"http://corp/v3", "template-user", "", "Corp"},
+		"override": {"http://corp/v3", "entry-user", "pw", "Corp"},
+		"flat":     {"http://corp/v3", "flat-user", "", "Corp"},
+	} {
+		opts, err := conf.Get(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := [4]string{opts.IdentityEndpoint, opts.Username, opts.Password, opts.DomainName}; got != want {
+			t.Errorf("Get(%s) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestAuthTemplatesUndefined(t *testing.T) {
+	for _, doc := range []string{
+		"clouds:\n  a:\n    auth_ref: missing\n    auth: {auth_url: http://a/v3}\n",
+		"auth_templates:\n  corp: {auth_url: http://corp/v3}\nclouds:\n  a:\n    auth_ref: missing\n",
+	} {
+		_, err := FromBytes([]byte(doc))
+		parseErr, ok := err.(*ParseError)
+		want := "cloud `a`: auth_ref `missing` is not defined in auth_templates"
+		if !ok || parseErr.Err.Error() != want {
+			t.Errorf("FromBytes(%q) error = %v, want %s", doc, err, want)
+		}
+	}
+}
+
+func TestAuthTemplatesDoNotLeak(t *testing.T) {
+	conf, err := FromBytes([]byte(`
+auth_templates:
+  corp: {auth_url: http://corp/v3, username: shared}
+clouds:
+  a:
+    auth_ref: corp
+    auth: {username: a}
+  b:
+    auth_ref: corp
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opts, err := conf.Get("b"); err != nil || opts.Username != "shared" {
+		t.Errorf("Get(b) = %+v, %v; want the template's username", opts, err)
+	}
+}
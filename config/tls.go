@@ -0,0 +1,144 @@
+package config
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// tlsSettings holds a cloud’s TLS-related fields.
+type tlsSettings struct {
+	insecure   bool
+	cacert     string
+	capath     string
+	cert       string
+	key        string
+	serverName string
+}
+
+// tlsConfig returns the TLS configuration for connecting to a cloud, forcing
+// InsecureSkipVerify if insecure is set. This returns nil if the cloud needs
+// no TLS customization, so the default transport can be used as-is.
+func (s tlsSettings) tlsConfig(insecure bool) (*tls.Config, error) {
+	if s == (tlsSettings{}) && !insecure {
+		return nil, nil
+	}
+	tc := &tls.Config{
+		InsecureSkipVerify: s.insecure || insecure,
+		ServerName:         s.serverName,
+	}
+	if s.cacert != "" {
+		b, where := []byte(s.cacert), "inline cacert"
+		if !isInlinePEM(s.cacert) {
+			var err error
+			if b, err = ioutil.ReadFile(s.cacert); err != nil {
+				return nil, errors.New("config: cannot read cacert: " + err.Error())
+			}
+			where = "cacert " + s.cacert
+		}
+		tc.RootCAs = x509.NewCertPool()
+		if !tc.RootCAs.AppendCertsFromPEM(b) {
+			return nil, errors.New("config: no PEM certificates found in " + where)
+		}
+	}
+	if s.capath != "" {
+		if tc.RootCAs == nil {
+			tc.RootCAs = x509.NewCertPool()
+		}
+		if err := appendCertsFromDir(tc.RootCAs, s.capath); err != nil {
+			return nil, err
+		}
+	}
+	if s.cert != "" || s.key != "" {
+		if s.cert == "" || s.key == "" {
+			return nil, errors.New("config: cert and key must be set together")
+		}
+		pair, err := tls.LoadX509KeyPair(s.cert, s.key)
+		if err != nil {
+			return nil, errors.New("config: cannot load client certificate: " + err.Error())
+		}
+		tc.Certificates = []tls.Certificate{pair}
+	}
+	return tc, nil
+}
+
+// TLSConfig satisfies the Config interface.
+func (c *configImpl) TLSConfig(name string) (*tls.Config, error) {
+	v, err := c.cloud(name)
+	if err != nil {
+		return nil, err
+	}
+	return v.tls.tlsConfig(false)
+}
+
+// appendCertsFromDir adds the certificates of every PEM file in a directory
+// to pool. Subdirectories and files holding no certificates, such as a
+// README kept alongside, are skipped, but a directory with no certificates at
+// all is an error.
+func appendCertsFromDir(pool *x509.CertPool, dir string) error {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return errors.New("config: cannot read capath: " + err.Error())
+	}
+	found := false
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		b, err := ioutil.ReadFile(filepath.Join(dir, e.Name()))
+		if err != nil {
+			continue
+		}
+		if pool.AppendCertsFromPEM(b) {
+			found = true
+		}
+	}
+	if !found {
+		return errors.New("config: no PEM certificates found in capath " + dir)
+	}
+	return nil
+}
+
+// isInlinePEM reports whether a cacert value holds PEM certificates itself
+// rather than naming a file.
+func isInlinePEM(s string) bool {
+	return strings.Contains(s, "-----BEGIN ")
+}
+
+// CheckFiles satisfies the Config interface.
+func (c *configImpl) CheckFiles(name string) error {
+	v, err := c.cloud(name)
+	if err != nil {
+		return err
+	}
+	var bad []string
+	for _, f := range []struct{ field, path string }{
+		{"cacert", v.tls.cacert},
+		{"capath", v.tls.capath},
+		{"cert", v.tls.cert},
+		{"key", v.tls.key},
+	} {
+		if f.path == "" || isInlinePEM(f.path) {
+			continue
+		}
+		fh, err := os.Open(f.path)
+		if err != nil {
+			reason := err.Error()
+			if pe, ok := err.(*os.PathError); ok {
+				reason = pe.Err.Error()
+			}
+			bad = append(bad, f.field+" `"+f.path+"` ("+reason+")")
+			continue
+		}
+		fh.Close()
+	}
+	if len(bad) > 0 {
+		s := "config: cloud `" + name + "` references unreadable files: " + strings.Join(bad, ", ")
+		return errors.New(s)
+	}
+	return nil
+}
@@ -0,0 +1,238 @@
+package config
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"log"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTLSServerName(t *testing.T) {
+	conf, err := FromBytes([]byte(`
+clouds:
+  lb:
+    tls_server_name: api.internal.example.com
+    auth_type: admin_token
+    auth:
+      token: tok
+      endpoint: https://lb.example.com/compute
+  plain:
+    auth: {auth_url: https://keystone.example.com/v3}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tc, err := conf.TLSConfig("lb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tc == nil || tc.ServerName != "api.internal.example.com" || tc.InsecureSkipVerify {
+		t.Errorf("TLSConfig(lb) = %+v, want ServerName set and verification on", tc)
+	}
+	if tc, err := conf.TLSConfig("plain"); err != nil || tc != nil {
+		t.Errorf("TLSConfig(plain) = %+v, %v; want nil", tc, err)
+	}
+
+	p, err := conf.Authenticate("lb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr, ok := p.HTTPClient.Transport.(*http.Transport)
+	if !ok || tr.TLSClientConfig == nil || tr.TLSClientConfig.ServerName != "api.internal.example.com" {
+		t.Errorf("Authenticate(lb) transport = %#v, want ServerName set", p.HTTPClient.Transport)
+	}
+}
+
+// tlsServer starts a TLS server with a certificate of its own, for
+// example.com, and returns it with the certificate as PEM.
+func tlsServer(t *testing.T) (*httptest.Server, string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "example.com"},
+		DNSNames:              []string{"example.com"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewUnstartedServer(http.NotFoundHandler())
+	srv.Config.ErrorLog = log.New(io.Discard, "", 0)
+	srv.TLS = &tls.Config{Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key}}}
+	srv.StartTLS()
+	t.Cleanup(srv.Close)
+	return srv, string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+}
+
+// dialTLS reports whether a TLS handshake with srv succeeds using tc.
+func dialTLS(t *testing.T, srv *httptest.Server, tc *tls.Config) error {
+	t.Helper()
+	tc = tc.Clone()
+	tc.ServerName = "example.com"
+	conn, err := tls.Dial("tcp", strings.TrimPrefix(srv.URL, "https://"), tc)
+	if err == nil {
+		conn.Close()
+	}
+	return err
+}
+
+func TestRelativeCertPaths(t *testing.T) {
+	wd := isolateSearch(t)
+	srv, cert := tlsServer(t)
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "ca.pem"), cert)
+	doc := "clouds:\n  a:\n    cacert: ./ca.pem\n    auth: {auth_url: https://a/v3}\n"
+	path := filepath.Join(dir, "clouds.yaml")
+	writeFile(t, path, doc)
+
+	// In a file, the path is relative to the file’s directory, not the
+	// working directory, which holds no ca.pem.
+	conf, err := FromFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tc, err := conf.TLSConfig("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := dialTLS(t, srv, tc); err != nil {
+		t.Errorf("handshake with the relative cacert from a file: %v", err)
+	}
+
+	// Content with no file behind it falls back to the working directory.
+	conf, err = FromBytes([]byte(doc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := conf.TLSConfig("a"); err == nil {
+		t.Error("TLSConfig from bytes found ca.pem outside the working directory")
+	}
+	writeFile(t, filepath.Join(wd, "ca.pem"), cert)
+	if tc, err = conf.TLSConfig("a"); err != nil {
+		t.Fatal(err)
+	}
+	if err := dialTLS(t, srv, tc); err != nil {
+		t.Errorf("handshake with the relative cacert from bytes: %v", err)
+	}
+}
+
+func TestAbsoluteCertPath(t *testing.T) {
+	isolateSearch(t)
+	srv, cert := tlsServer(t)
+	ca := filepath.Join(t.TempDir(), "ca.pem")
+	writeFile(t, ca, cert)
+	path := filepath.Join(t.TempDir(), "clouds.yaml")
+	writeFile(t, path, "clouds:\n  a:\n    cacert: "+ca+"\n    auth: {auth_url: https://a/v3}\n")
+	conf, err := FromFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tc, err := conf.TLSConfig("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := dialTLS(t, srv, tc); err != nil {
+		t.Errorf("handshake with an absolute cacert: %v", err)
+	}
+}
+
+func TestCAPath(t *testing.T) {
+	isolateSearch(t)
+	srv1, cert1 := tlsServer(t)
+	srv2, cert2 := tlsServer(t)
+	other, _ := tlsServer(t)
+	dir := t.TempDir()
+	capath := filepath.Join(dir, "certs")
+	if err := os.MkdirAll(filepath.Join(capath, "nested"), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, filepath.Join(capath, "one.pem"), cert1)
+	writeFile(t, filepath.Join(capath, "two.crt"), cert2)
+	writeFile(t, filepath.Join(capath, "README"), "Trusted CAs for the lab clouds.\n")
+	path := filepath.Join(dir, "clouds.yaml")
+	writeFile(t, path, "clouds:\n  a:\n    capath: certs\n    auth: {auth_url: https://a/v3}\n")
+
+	conf, err := FromFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tc, err := conf.TLSConfig("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, srv := range []*httptest.Server{srv1, srv2} {
+		if err := dialTLS(t, srv, tc); err != nil {
+			t.Errorf("handshake with CA %d from capath: %v", i+1, err)
+		}
+	}
+	if err := dialTLS(t, other, tc); err == nil {
+		t.Error("handshake with a CA outside capath succeeded")
+	}
+}
+
+func TestCAPathWithCACert(t *testing.T) {
+	isolateSearch(t)
+	srv1, cert1 := tlsServer(t)
+	srv2, cert2 := tlsServer(t)
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "ca.pem"), cert1)
+	if err := os.Mkdir(filepath.Join(dir, "certs"), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, filepath.Join(dir, "certs", "two.pem"), cert2)
+	path := filepath.Join(dir, "clouds.yaml")
+	writeFile(t, path, "clouds:\n  a:\n    cacert: ca.pem\n    capath: certs\n    auth: {auth_url: https://a/v3}\n")
+	conf, err := FromFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tc, err := conf.TLSConfig("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, srv := range []*httptest.Server{srv1, srv2} {
+		if err := dialTLS(t, srv, tc); err != nil {
+			t.Errorf("handshake with CA %d from cacert and capath: %v", i+1, err)
+		}
+	}
+}
+
+func TestCAPathErrors(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "README"), "no certificates here\n")
+	for capath, want := range map[string]string{
+		dir:                           "config: no PEM certificates found in capath " + dir,
+		filepath.Join(dir, "missing"): "config: cannot read capath: ",
+	} {
+		conf, err := FromBytes([]byte("clouds:\n  a:\n    capath: " + capath + "\n    auth: {auth_url: https://a/v3}\n"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := conf.TLSConfig("a"); err == nil || !strings.HasPrefix(err.Error(), want) {
+			t.Errorf("TLSConfig with capath %s error = %v, want %s", capath, err, want)
+		}
+	}
+}
@@ -0,0 +1,35 @@
+package config
+
+import (
+	"github.com/gophercloud/gophercloud"
+)
+
+// tokenEndpointAuthType is the auth type of a cloud that uses a token obtained
+// elsewhere against a fixed service endpoint, without the identity service.
+const tokenEndpointAuthType = "admin_token"
+
+// tokenEndpointClient returns a ProviderClient that sends a cloud’s token as
+// it is, without authenticating or reading the service catalog.
+func tokenEndpointClient(name string, v cloud, co *clientOptions) (*gophercloud.ProviderClient, error) {
+	if err := validate(name, v, requiredFields[tokenEndpointAuthType]); err != nil {
+		return nil, err
+	}
+	p := &gophercloud.ProviderClient{}
+	if err := setTransport(p, v, co); err != nil {
+		return nil, err
+	}
+	p.UseTokenLock()
+	p.SetToken(v.auth.TokenID)
+	return p, nil
+}
+
+// tokenEndpointServiceClient returns a ServiceClient for a cloud’s endpoint,
+// taking the place of a catalog lookup.
+func tokenEndpointServiceClient(p *gophercloud.ProviderClient, v cloud, service, microversion string) *gophercloud.ServiceClient {
+	return &gophercloud.ServiceClient{
+		ProviderClient: p,
+		Endpoint:       gophercloud.NormalizeURL(v.endpoint),
+		Type:           service,
+		Microversion:   microversion,
+	}
+}
@@ -0,0 +1,36 @@
+package config
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+// readTokenFile returns the token held in a token_file, without surrounding
+// whitespace. A missing file yields an empty token rather than an error, so
+// that Validate can report it for clouds that need a token.
+func readTokenFile(path string) (string, error) {
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", errors.New("cannot read token_file: " + err.Error())
+	}
+	return strings.TrimSpace(string(b)), nil
+}
+
+// reloadToken returns the cloud with its token re-read from its token_file,
+// if it has one.
+func (v cloud) reloadToken(name string) (cloud, error) {
+	if v.tokenFile == "" {
+		return v, nil
+	}
+	token, err := readTokenFile(v.tokenFile)
+	if err != nil {
+		return cloud{}, errors.New("config: cloud `" + name + "`: " + err.Error())
+	}
+	v.auth.TokenID = token
+	return v, nil
+}
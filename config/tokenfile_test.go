@@ -0,0 +1,72 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func tokenFileClouds(path string) []byte {
+	return []byte(`
+clouds:
+  a:
+    auth_type: token
+    auth:
+      auth_url: http://a/v3
+      token_file: ` + path + `
+`)
+}
+
+func TestTokenFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "token")
+	writeFile(t, path, "  tok-1\n\n")
+	conf, err := FromBytes(tokenFileClouds(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opts, err := conf.Get("a"); err != nil || opts.TokenID != "tok-1" {
+		t.Errorf("Get(a) = %+v, %v; want the trimmed token tok-1", opts, err)
+	}
+
+	// Without WithTokenFileReload, the token read at load time is kept.
+	writeFile(t, path, "tok-2\n")
+	if opts, err := conf.Get("a"); err != nil || opts.TokenID != "tok-1" {
+		t.Errorf("Get(a) after a change = %+v, %v; want tok-1", opts, err)
+	}
+}
+
+func TestTokenFileReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "token")
+	writeFile(t, path, "tok-1\n")
+	conf, err := FromBytes(tokenFileClouds(path), WithTokenFileReload())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, token := range []string{"tok-2", "tok-3"} {
+		writeFile(t, path, token+"\r\n")
+		if opts, err := conf.Get("a"); err != nil || opts.TokenID != token {
+			t.Errorf("Get(a) = %+v, %v; want the reloaded token %s", opts, err, token)
+		}
+	}
+
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	err = conf.Validate("a")
+	if err == nil || !strings.Contains(err.Error(), "token_file `"+path+"` is missing or empty") {
+		t.Errorf("Validate(a) with the token_file removed = %v, want it to name the token_file", err)
+	}
+}
+
+func TestTokenFileMissing(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "missing")
+	conf, err := FromBytes(tokenFileClouds(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = conf.Validate("a")
+	if want := "config: cloud `a` is missing token (token_file `" + path + "` is missing or empty)"; err == nil || err.Error() != want {
+		t.Errorf("Validate(a) = %v, want %s", err, want)
+	}
+}
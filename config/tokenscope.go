@@ -0,0 +1,111 @@
+package config
+
+import (
+	"context"
+	"errors"
+	tokens2 "github.com/gophercloud/gophercloud/openstack/identity/v2/tokens"
+	tokens3 "github.com/gophercloud/gophercloud/openstack/identity/v3/tokens"
+)
+
+// ScopeInfo describes what a token was scoped to when it was issued, as
+// reported by the identity service.
+type ScopeInfo struct {
+	// ProjectID and ProjectName identify the project of a project-scoped
+	// token, and ProjectDomainID and ProjectDomainName its domain. They
+	// are empty for a token not scoped to a project.
+	ProjectID         string
+	ProjectName       string
+	ProjectDomainID   string
+	ProjectDomainName string
+
+	// DomainID and DomainName identify the domain of a domain-scoped
+	// token. They are empty for other tokens.
+	DomainID   string
+	DomainName string
+
+	// UserID and UserName identify the user the token was issued to.
+	UserID   string
+	UserName string
+
+	// Roles lists the names of the roles the token carries, in the order
+	// the identity service gave them.
+	Roles []string
+}
+
+// TokenScope satisfies the Config interface.
+func (c *configImpl) TokenScope(ctx context.Context, name string, opts ...ClientOption) (ScopeInfo, error) {
+	v, err := c.cloudWithSecrets(name)
+	if err != nil {
+		return ScopeInfo{}, err
+	}
+	p, err := authenticate(ctx, name, v, newClientOptions(opts))
+	if err != nil {
+		return ScopeInfo{}, err
+	}
+	var s ScopeInfo
+	switch r := p.GetAuthResult().(type) {
+	case tokens3.CreateResult:
+		err = scopeV3(r, &s)
+	case tokens2.CreateResult:
+		err = scopeV2(r, &s)
+	default:
+		return ScopeInfo{}, errors.New("config: token scope of cloud `" + name + "` is not available")
+	}
+	if err != nil {
+		return ScopeInfo{}, errors.New("config: " + err.Error())
+	}
+	return s, nil
+}
+
+// scopeV3 fills in s from an identity v3 token.
+func scopeV3(r tokens3.CreateResult, s *ScopeInfo) error {
+	pr, err := r.ExtractProject()
+	if err != nil {
+		return err
+	}
+	if pr != nil {
+		s.ProjectID, s.ProjectName = pr.ID, pr.Name
+		s.ProjectDomainID, s.ProjectDomainName = pr.Domain.ID, pr.Domain.Name
+	}
+	d, err := r.ExtractDomain()
+	if err != nil {
+		return err
+	}
+	if d != nil {
+		s.DomainID, s.DomainName = d.ID, d.Name
+	}
+	u, err := r.ExtractUser()
+	if err != nil {
+		return err
+	}
+	if u != nil {
+		s.UserID, s.UserName = u.ID, u.Name
+	}
+	roles, err := r.ExtractRoles()
+	if err != nil {
+		return err
+	}
+	for _, role := range roles {
+		s.Roles = append(s.Roles, role.Name)
+	}
+	return nil
+}
+
+// scopeV2 fills in s from an identity v2 token, whose project is called a
+// tenant and which has no domains.
+func scopeV2(r tokens2.CreateResult, s *ScopeInfo) error {
+	t, err := r.ExtractToken()
+	if err != nil {
+		return err
+	}
+	s.ProjectID, s.ProjectName = t.Tenant.ID, t.Tenant.Name
+	u, err := tokens2.GetResult{CreateResult: r}.ExtractUser()
+	if err != nil {
+		return err
+	}
+	s.UserID, s.UserName = u.ID, u.Name
+	for _, role := range u.Roles {
+		s.Roles = append(s.Roles, role.Name)
+	}
+	return nil
+}
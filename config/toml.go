@@ -0,0 +1,69 @@
+//go:build toml
+// +build toml
+
+package config
+
+import (
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v2"
+	"os"
+)
+
+// FromTOML returns an initialized *Config from TOML content using the same
+// schema as clouds.yaml, with each cloud’s credentials in a
+// [clouds.<name>.auth] table:
+//
+//	[clouds.foo.auth]
+//	auth_url = "https://keystone.example.com:5000/v2.0"
+//	username = "admin"
+//
+// This returns a *ParseError if the content is not valid TOML or does not
+// define any clouds.
+//
+// TOML support is only built with the toml build tag.
+func FromTOML(b []byte, opts ...Option) (Config, error) {
+	o, err := newOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+	return fromTOML(b, "", o)
+}
+
+// FromTOMLFile returns an initialized *Config from a TOML file. (See FromTOML.)
+func FromTOMLFile(path string, opts ...Option) (Config, error) {
+	o, err := newOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, &FileError{path, err}
+	}
+	defer f.Close()
+	b, err := readAll(f, path, o)
+	if err != nil {
+		return nil, err
+	}
+	return fromTOML(b, path, o)
+}
+
+// fromTOML decodes TOML content and hands it to the clouds.yaml parser, so
+// both formats share a single schema.
+func fromTOML(b []byte, path string, o *options) (Config, error) {
+	y, err := tomlToYAML(b)
+	if err != nil {
+		return nil, &ParseError{path, err}
+	}
+	yo := *o
+	yo.format = FormatYAML
+	return parse(y, path, &yo)
+}
+
+// tomlToYAML converts TOML content to the equivalent YAML.
+func tomlToYAML(b []byte) ([]byte, error) {
+	doc := map[string]interface{}{}
+	if err := toml.Unmarshal(b, &doc); err != nil {
+		return nil, err
+	}
+	return yaml.Marshal(doc)
+}
@@ -0,0 +1,13 @@
+//go:build !toml
+// +build !toml
+
+package config
+
+import (
+	"errors"
+)
+
+// tomlToYAML reports that TOML support was not built in.
+func tomlToYAML(b []byte) ([]byte, error) {
+	return nil, errors.New("TOML support is not built in; build with the toml tag")
+}
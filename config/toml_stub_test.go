@@ -0,0 +1,24 @@
+//go:build !toml
+// +build !toml
+
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFormatTOMLNotBuiltIn(t *testing.T) {
+	_, err := FromBytes([]byte("[clouds.a.auth]\nauth_url = \"http://a/v3\"\n"), WithFormat(FormatTOML))
+	if _, ok := err.(*ParseError); !ok {
+		t.Fatalf("FromBytes with FormatTOML error = %v, want a *ParseError", err)
+	}
+	if !strings.Contains(err.Error(), "TOML support is not built in") {
+		t.Errorf("FromBytes with FormatTOML error = %v, want it to say TOML support is not built in", err)
+	}
+
+	// Without TOML support, FormatAuto reports the YAML error.
+	if _, err := FromBytes([]byte("[clouds.a.auth]\nauth_url = \"http://a/v3\"\n")); err == nil || strings.Contains(err.Error(), "TOML") {
+		t.Errorf("FromBytes with TOML content error = %v, want the YAML error", err)
+	}
+}
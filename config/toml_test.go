@@ -0,0 +1,110 @@
+//go:build toml
+// +build toml
+
+package config
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+const tomlClouds = `
+[clouds.a]
+region_name = "r1"
+
+[clouds.a.auth]
+auth_url = "http://a/v3"
+username = "ua"
+password = "pa"
+project_name = "pr"
+user_domain_name = "ud"
+project_domain_name = "pd"
+`
+
+const tomlCloudsYAML = `
+clouds:
+  a:
+    region_name: r1
+    auth:
+      auth_url: http://a/v3
+      username: ua
+      password: pa
+      project_name: pr
+      user_domain_name: ud
+      project_domain_name: pd
+`
+
+func TestFromTOMLMatchesYAML(t *testing.T) {
+	yconf, err := FromBytes([]byte(tomlCloudsYAML))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := yconf.Get("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "clouds.toml")
+	writeFile(t, path, tomlClouds)
+	for name, load := range map[string]func() (Config, error){
+		"FromTOML":     func() (Config, error) { return FromTOML([]byte(tomlClouds)) },
+		"FromTOMLFile": func() (Config, error) { return FromTOMLFile(path) },
+		"FormatTOML":   func() (Config, error) { return FromBytes([]byte(tomlClouds), WithFormat(FormatTOML)) },
+		"FormatAuto":   func() (Config, error) { return FromBytes([]byte(tomlClouds)) },
+	} {
+		conf, err := load()
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		if got, err := conf.Get("a"); err != nil || !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: Get(a) = %+v, %v; want %+v as from YAML", name, got, err, want)
+		}
+		if eo, err := conf.EndpointOpts("a", ""); err != nil || eo.Region != "r1" {
+			t.Errorf("%s: EndpointOpts(a) = %+v, %v; want region r1", name, eo, err)
+		}
+	}
+}
+
+func TestFromTOMLInvalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "clouds.toml")
+	writeFile(t, path, "[clouds.a\n")
+	for name, load := range map[string]func() (Config, error){
+		"FromTOML":     func() (Config, error) { return FromTOML([]byte("[clouds.a\n")) },
+		"FromTOMLFile": func() (Config, error) { return FromTOMLFile(path) },
+		"FormatTOML":   func() (Config, error) { return FromBytes([]byte(validClouds), WithFormat(FormatTOML)) },
+	} {
+		if _, err := load(); err == nil {
+			t.Errorf("%s with invalid TOML: no error", name)
+		} else if _, ok := err.(*ParseError); !ok {
+			t.Errorf("%s with invalid TOML error = %v, want a *ParseError", name, err)
+		}
+	}
+	if _, err := FromTOMLFile(path); err.(*ParseError).File != path {
+		t.Errorf("FromTOMLFile error = %v, want it to name %s", err, path)
+	}
+}
+
+func TestFormatAutoFallsBackToTOML(t *testing.T) {
+	// YAML rejects this document, as its first line is a flow sequence
+	// followed by more content, so it only loads as TOML.
+	conf, err := FromBytes([]byte("[clouds.b.auth]\nauth_url = \"http://b/v3\"\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opts, err := conf.Get("b"); err != nil || opts.IdentityEndpoint != "http://b/v3" {
+		t.Errorf("Get(b) = %+v, %v; want the TOML auth_url", opts, err)
+	}
+
+	// Content that is neither reports the YAML error, and WithFormat(FormatYAML)
+	// turns the fallback off.
+	if _, err := FromBytes([]byte("[clouds.b.auth]\nauth_url = \"http://b/v3\"\n"), WithFormat(FormatYAML)); err == nil {
+		t.Error("FromBytes with FormatYAML and TOML content: no error")
+	}
+	if _, err := FromBytes([]byte("clouds: [\n")); err == nil {
+		t.Error("FromBytes with neither YAML nor TOML: no error")
+	} else if _, ok := err.(*ParseError); !ok {
+		t.Errorf("FromBytes with neither YAML nor TOML error = %v, want a *ParseError", err)
+	}
+}
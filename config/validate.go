@@ -0,0 +1,193 @@
+package config
+
+import (
+	"errors"
+	"strings"
+)
+
+// defaultAuthType is the auth type of a cloud entry that omits auth_type,
+// unless WithDefaultAuthType sets another.
+const defaultAuthType = "password"
+
+// fields maps each clouds.yaml field name understood by ValidateWith to a
+// function returning its value in a parsed cloud. Aliases such as
+// tenant_name and project_name share a value.
+var fields = map[string]func(cloud) string{
+	"auth_type":                     func(v cloud) string { return v.effectiveAuthType() },
+	"auth_url":                      func(v cloud) string { return v.auth.IdentityEndpoint },
+	"region_name":                   func(v cloud) string { return v.region },
+	"username":                      func(v cloud) string { return v.auth.Username },
+	"user_id":                       func(v cloud) string { return v.auth.UserID },
+	"password":                      func(v cloud) string { return v.auth.Password },
+	"project_name":                  func(v cloud) string { return v.auth.TenantName },
+	"project_id":                    func(v cloud) string { return v.auth.TenantID },
+	"tenant_name":                   func(v cloud) string { return v.auth.TenantName },
+	"tenant_id":                     func(v cloud) string { return v.auth.TenantID },
+	"domain_name":                   func(v cloud) string { return v.auth.DomainName },
+	"domain_id":                     func(v cloud) string { return v.auth.DomainID },
+	"user_domain_name":              func(v cloud) string { return v.auth.DomainName },
+	"user_domain_id":                func(v cloud) string { return v.auth.DomainID },
+	"project_domain_name":           func(v cloud) string { return v.projectDomainName() },
+	"project_domain_id":             func(v cloud) string { return v.projectDomainID() },
+	"token":                         func(v cloud) string { return v.auth.TokenID },
+	"endpoint":                      func(v cloud) string { return v.endpoint },
+	"passcode":                      func(v cloud) string { return v.auth.Passcode },
+	"application_credential_id":     func(v cloud) string { return v.auth.ApplicationCredentialID },
+	"application_credential_name":   func(v cloud) string { return v.auth.ApplicationCredentialName },
+	"application_credential_secret": func(v cloud) string { return v.auth.ApplicationCredentialSecret },
+}
+
+// requiredFields maps each supported auth type to the fields Validate
+// requires for it.
+var requiredFields = map[string][]string{
+	"password":    {"auth_url", "username|user_id", "password"},
+	"v2password":  {"auth_url", "username|user_id", "password"},
+	"v3password":  {"auth_url", "username|user_id", "password"},
+	"token":       {"auth_url", "token"},
+	"v2token":     {"auth_url", "token"},
+	"v3token":     {"auth_url", "token"},
+	"v3totp":      {"auth_url", "username|user_id", "passcode"},
+	"admin_token": {"endpoint", "token"},
+	"v3multifactor": {
+		"auth_url",
+		"username|user_id",
+		"password",
+		"passcode",
+	},
+	"v3applicationcredential": {
+		"auth_url",
+		"application_credential_id|application_credential_name",
+		"application_credential_secret",
+	},
+}
+
+// RequiredFields returns the fields Validate requires for an auth type, or
+// nil if the auth type is not supported. The result is a copy, so callers may
+// extend it and pass it to ValidateWith:
+//
+//	required := append(config.RequiredFields("password"), "region_name")
+//	err := c.ValidateWith("prod", required)
+func RequiredFields(authType string) []string {
+	req, ok := requiredFields[authType]
+	if !ok {
+		return nil
+	}
+	return append([]string(nil), req...)
+}
+
+// Validate satisfies the Config interface.
+func (c *configImpl) Validate(name string) error {
+	v, err := c.cloudWithSecrets(name)
+	if err != nil {
+		return err
+	}
+	return validateDefault(name, v)
+}
+
+// Invalid satisfies the Config interface.
+func (c *configImpl) Invalid() map[string]error {
+	var m map[string]error
+	for _, name := range c.Names() {
+		v, err := c.cloud(name)
+		if err != nil {
+			// The cloud was removed since listing the names.
+			continue
+		}
+		if v, err = c.withPrompted(name, v); err != nil {
+			if m == nil {
+				m = map[string]error{}
+			}
+			m[name] = err
+			continue
+		}
+		if err := validateDefault(name, v); err != nil {
+			if m == nil {
+				m = map[string]error{}
+			}
+			m[name] = err
+		}
+	}
+	return m
+}
+
+// validateDefault checks that a cloud sets every field its auth type requires.
+func validateDefault(name string, v cloud) error {
+	t := v.effectiveAuthType()
+	req, ok := requiredFields[t]
+	if !ok {
+		return errors.New("config: cloud `" + name + "` has unsupported auth_type `" + t + "`")
+	}
+	return validate(name, v, req)
+}
+
+// ValidateWith satisfies the Config interface.
+func (c *configImpl) ValidateWith(name string, required []string) error {
+	v, err := c.cloudWithSecrets(name)
+	if err != nil {
+		return err
+	}
+	return validate(name, v, required)
+}
+
+// validate checks that every required field is set, returning one error
+// listing all that are missing.
+func validate(name string, v cloud, required []string) error {
+	var missing []string
+	for _, r := range required {
+		set := false
+		for _, f := range strings.Split(r, "|") {
+			get, ok := fields[f]
+			if !ok {
+				return errors.New("config: unknown field `" + f + "`")
+			}
+			if get(v) != "" {
+				set = true
+			}
+		}
+		if !set {
+			m := strings.Replace(r, "|", " or ", -1)
+			if r == "token" && v.tokenFile != "" {
+				m += " (token_file `" + v.tokenFile + "` is missing or empty)"
+			}
+			missing = append(missing, m)
+		}
+	}
+	if len(missing) > 0 {
+		s := "config: cloud `" + name + "` is missing " + strings.Join(missing, ", ")
+		return errors.New(s)
+	}
+	return nil
+}
+
+// effectiveAuthType returns the cloud’s auth type, applying the default.
+func (v cloud) effectiveAuthType() string {
+	if v.authType == "" {
+		return defaultAuthType
+	}
+	return v.authType
+}
+
+// projectDomain is a cloud’s explicit project domain. It is kept apart from
+// the scope of the cloud’s options, which gophercloud only accepts for a
+// project given by name, so that a project given by ID keeps its domain too.
+type projectDomain struct {
+	id, name string
+}
+
+// projectDomainName returns the cloud’s explicit project domain name, or that
+// of its scope if it was built from options rather than an entry.
+func (v cloud) projectDomainName() string {
+	if v.projDomain.name == "" && v.auth.Scope != nil {
+		return v.auth.Scope.DomainName
+	}
+	return v.projDomain.name
+}
+
+// projectDomainID returns the cloud’s explicit project domain ID, or that of
+// its scope if it was built from options rather than an entry.
+func (v cloud) projectDomainID() string {
+	if v.projDomain.id == "" && v.auth.Scope != nil {
+		return v.auth.Scope.DomainID
+	}
+	return v.projDomain.id
+}
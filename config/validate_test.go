@@ -0,0 +1,65 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestProjectDomainWithProjectID(t *testing.T) {
+	conf, err := FromBytes([]byte(`
+clouds:
+  byid:
+    auth:
+      auth_url: http://a/v3
+      username: u
+      password: p
+      project_id: 0123abcd
+      project_domain_name: Engineering
+  byname:
+    auth:
+      auth_url: http://a/v3
+      username: u
+      password: p
+      project_name: proj
+      project_domain_id: d-42
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, field := range map[string]string{"byid": "project_domain_name", "byname": "project_domain_id"} {
+		if err := conf.ValidateWith(name, []string{field}); err != nil {
+			t.Errorf("ValidateWith(%s, %s) = %v", name, field, err)
+		}
+		b, err := conf.ExportCloud(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(string(b), field+":") {
+			t.Errorf("ExportCloud(%s) =\n%s\nwant %s", name, b, field)
+		}
+		fields, err := conf.SetFields(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(strings.Join(fields, " "), "auth."+field) {
+			t.Errorf("SetFields(%s) = %q, want auth.%s", name, fields, field)
+		}
+	}
+
+	// The exported entry loads back with its project domain.
+	b, err := conf.ExportCloud("byid")
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, err := FromBytes(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := again.ValidateWith("byid", []string{"project_id", "project_domain_name"}); err != nil {
+		t.Errorf("ValidateWith after a round trip = %v", err)
+	}
+
+	if err := conf.ValidateWith("byid", []string{"project_domain_id"}); err == nil || err.Error() != "config: cloud `byid` is missing project_domain_id" {
+		t.Errorf("ValidateWith(byid, project_domain_id) = %v", err)
+	}
+}
@@ -0,0 +1,46 @@
+package config
+
+import (
+	"context"
+	"sync"
+)
+
+// maxConcurrentVerify caps how many clouds VerifyAll authenticates against at
+// once, so checking many clouds that share a Keystone doesn’t overwhelm it.
+const maxConcurrentVerify = 4
+
+// VerifyAll satisfies the Config interface.
+func (c *configImpl) VerifyAll(ctx context.Context) map[string]error {
+	names := c.Names()
+	results := make(map[string]error, len(names))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, maxConcurrentVerify)
+	for _, name := range names {
+		v, err := c.cloudWithSecrets(name)
+		if err == nil {
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				err = ctx.Err()
+			}
+		}
+		if err != nil {
+			mu.Lock()
+			results[name] = err
+			mu.Unlock()
+			continue
+		}
+		wg.Add(1)
+		go func(name string, v cloud) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			_, err := authenticate(ctx, name, v, newClientOptions(nil))
+			mu.Lock()
+			results[name] = err
+			mu.Unlock()
+		}(name, v)
+	}
+	wg.Wait()
+	return results
+}
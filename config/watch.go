@@ -0,0 +1,81 @@
+package config
+
+import (
+	"context"
+	"os"
+	"time"
+)
+
+// defaultWatchInterval is how often Watch checks its file for changes, unless
+// WithWatchInterval sets another interval.
+const defaultWatchInterval = 2 * time.Second
+
+// Watch loads a clouds.yaml file, as FromFile does, and then reloads it each
+// time it changes until ctx is done. The first Config sent on the returned
+// channel is the initial one; each later one reflects a change. If a reload
+// fails, its error is sent on the error channel instead and the watch goes on,
+// so a half-written file does not end it.
+//
+// The file is polled by path (see WithWatchInterval), so it is still followed
+// if it is deleted and recreated, as by editors that save by writing a new
+// file and renaming it into place; while it is missing, nothing is sent.
+//
+// When ctx is done, the watch stops and both channels are closed. Nothing else
+// is left running and no file is left open. Receivers should keep draining
+// both channels, as a send blocks until it is received or ctx is done.
+//
+// If the options are invalid or the initial load fails, this returns an error
+// and no channels.
+func Watch(ctx context.Context, path string, opts ...Option) (<-chan Config, <-chan error, error) {
+	o, err := newOptions(opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	last, _ := os.Stat(path)
+	c, err := FromFile(path, opts...)
+	if err != nil {
+		return nil, nil, err
+	}
+	configs := make(chan Config, 1)
+	errs := make(chan error)
+	configs <- c
+	go func() {
+		defer close(errs)
+		defer close(configs)
+		t := time.NewTicker(o.watchInterval)
+		defer t.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-t.C:
+			}
+			fi, err := os.Stat(path)
+			if err != nil || last != nil && sameVersion(last, fi) {
+				continue
+			}
+			last = fi
+			c, err := FromFile(path, opts...)
+			if err != nil {
+				select {
+				case errs <- err:
+				case <-ctx.Done():
+					return
+				}
+				continue
+			}
+			select {
+			case configs <- c:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return configs, errs, nil
+}
+
+// sameVersion reports whether two results of stat for a path describe the
+// same version of the same file.
+func sameVersion(a, b os.FileInfo) bool {
+	return os.SameFile(a, b) && a.ModTime().Equal(b.ModTime()) && a.Size() == b.Size()
+}
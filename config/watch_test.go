@@ -0,0 +1,139 @@
+package config
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+const watchTestInterval = 10 * time.Millisecond
+
+// receive returns the next Config sent on configs, failing the test if none
+// arrives in time.
+func receive(t *testing.T, configs <-chan Config) Config {
+	t.Helper()
+	select {
+	case c, ok := <-configs:
+		if !ok {
+			t.Fatal("configs closed early")
+		}
+		return c
+	case <-time.After(5 * time.Second):
+		t.Fatal("no Config received")
+	}
+	return nil
+}
+
+// waitClosed fails the test unless both channels close in time.
+func waitClosed(t *testing.T, configs <-chan Config, errs <-chan error) {
+	t.Helper()
+	timeout := time.After(5 * time.Second)
+	for configs != nil || errs != nil {
+		select {
+		case _, ok := <-configs:
+			if !ok {
+				configs = nil
+			}
+		case _, ok := <-errs:
+			if !ok {
+				errs = nil
+			}
+		case <-timeout:
+			t.Fatal("channels not closed after cancel")
+		}
+	}
+}
+
+func TestWatchReloadsOnChange(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "clouds.yaml")
+	writeFile(t, path, "clouds:\n  a:\n    auth: {auth_url: http://a}\n")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	configs, errs, err := Watch(ctx, path, WithWatchInterval(watchTestInterval))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if names := receive(t, configs).Names(); len(names) != 1 || names[0] != "a" {
+		t.Fatalf("initial Names() = %v, want [a]", names)
+	}
+
+	writeFile(t, path, "clouds:\n  a:\n    auth: {auth_url: http://a}\n  b:\n    auth: {auth_url: http://b}\n")
+	if names := receive(t, configs).Names(); len(names) != 2 {
+		t.Errorf("reloaded Names() = %v, want [a b]", names)
+	}
+
+	// A file replaced by renaming a new one into place is followed.
+	tmp := path + ".tmp"
+	writeFile(t, tmp, "clouds:\n  c:\n    auth: {auth_url: http://c}\n")
+	if err := os.Rename(tmp, path); err != nil {
+		t.Fatal(err)
+	}
+	if names := receive(t, configs).Names(); len(names) != 1 || names[0] != "c" {
+		t.Errorf("Names() after rename = %v, want [c]", names)
+	}
+
+	// A broken file reports an error and the watch goes on.
+	writeFile(t, path, "clouds: [\n")
+	select {
+	case err := <-errs:
+		if _, ok := err.(*ParseError); !ok {
+			t.Errorf("reload error = %v, want a *ParseError", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no reload error received")
+	}
+	writeFile(t, path, "clouds:\n  d:\n    auth: {auth_url: http://d}\n")
+	if names := receive(t, configs).Names(); len(names) != 1 || names[0] != "d" {
+		t.Errorf("Names() after recovery = %v, want [d]", names)
+	}
+
+	cancel()
+	waitClosed(t, configs, errs)
+}
+
+func TestWatchCancelMidWatch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "clouds.yaml")
+	writeFile(t, path, "clouds:\n  a:\n    auth: {auth_url: http://a}\n")
+	ctx, cancel := context.WithCancel(context.Background())
+	configs, errs, err := Watch(ctx, path, WithWatchInterval(watchTestInterval))
+	if err != nil {
+		t.Fatal(err)
+	}
+	receive(t, configs)
+
+	// Change the file without receiving, so the watch is blocked sending
+	// the reload when it is cancelled.
+	writeFile(t, path, "clouds:\n  b:\n    auth: {auth_url: http://b}\n")
+	time.Sleep(10 * watchTestInterval)
+	cancel()
+	waitClosed(t, configs, errs)
+}
+
+func TestWatchClosesAfterCancel(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "clouds.yaml")
+	writeFile(t, path, "clouds:\n  a:\n    auth: {auth_url: http://a}\n")
+	ctx, cancel := context.WithCancel(context.Background())
+	configs, errs, err := Watch(ctx, path, WithWatchInterval(watchTestInterval))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cancel()
+	// The initial Config is buffered, so it may still be received once.
+	waitClosed(t, configs, errs)
+	if _, ok := <-configs; ok {
+		t.Error("configs received a value after closing")
+	}
+}
+
+func TestWatchErrors(t *testing.T) {
+	if _, _, err := Watch(context.Background(), filepath.Join(t.TempDir(), "missing.yaml")); err == nil {
+		t.Error("Watch(missing) = nil error")
+	}
+	path := filepath.Join(t.TempDir(), "clouds.yaml")
+	writeFile(t, path, "clouds:\n  a:\n    auth: {auth_url: http://a}\n")
+	if _, _, err := Watch(context.Background(), path, WithWatchInterval(0)); err == nil {
+		t.Error("Watch with a zero interval = nil error")
+	}
+}